@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/linuxmk/eml-sender/emlparse"
+)
+
+// addressHeaders lists the address headers of -all-headers, in the order they are output
+var addressHeaders = []string{"From", "Sender", "Reply-To", "To", "Cc", "Bcc"}
+
+// parse every address header of an email(or stdin with "-") in a single pass over the header section
+// and output a json object keyed by the header name with the records of its addresses
+// Headers that are not present are left out, a header that does not pass the validation has a record with the error
+// An empty header(e.g. "Cc:" without a value) is not an error, like an empty Bcc: it has a record with empty_header
+// In -check mode only the errors are printed to stderr
+//
+// Returns a *parseError if the email can not be read, none of the address headers is present
+// or one of the headers does not pass the validation(errCheckFailed in -check mode)
+func doAllHeaders(parser *emlparse.Parser, filename string, opts outputOptions) error {
+
+	r, closer, err := openInput(filename)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	names := append(addressHeaders[:len(addressHeaders):len(addressHeaders)], "Date", "Subject")
+	values, err := emlparse.LocateHeaders(emlparse.DecodeInput(r), names)
+	if err != nil {
+		if errors.Is(err, emlparse.ErrEmptyHeaders) || errors.Is(err, emlparse.ErrEmptyInput) {
+			return &parseError{exitCode: exitHeaderMissing, err: err}
+		}
+		return &parseError{exitCode: exitIOError, err: err}
+	}
+
+	var buf bytes.Buffer
+	var failed error
+	found := 0
+
+	buf.WriteByte('{')
+	for _, header := range addressHeaders {
+		value, ok := values[header]
+		if !ok {
+			continue
+		}
+		found++
+
+		data := headerData{value: value, source: header, date: values["Date"], subject: values["Subject"], empty: value == ""}
+		infos, err := parseHeaderValue(parser, data)
+
+		if err != nil && failed == nil {
+			failed = fmt.Errorf("%s: %w", header, err)
+		}
+		if opts.check {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", header, err)
+			}
+			continue
+		}
+
+		jsonOut := buildJSONOutput(infos, err, opts)
+		annotateHeader(jsonOut, nil, data, opts)
+
+		records := make([]any, 0, len(jsonOut))
+		for _, record := range jsonOut {
+			records = append(records, outputRecord(record, opts.fields, opts.schema))
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(header)
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(createJSONOutput(records, ""))
+	}
+	buf.WriteByte('}')
+
+	if found == 0 {
+		return &parseError{exitCode: exitHeaderMissing, err: fmt.Errorf("address %w", emlparse.ErrHeaderMissing)}
+	}
+
+	if !opts.check {
+		fmt.Fprintf(opts.out, "%s\n", createJSONOutput(json.RawMessage(buf.Bytes()), opts.indent))
+	}
+
+	if failed != nil {
+		if opts.check {
+			return errCheckFailed
+		}
+		return &parseError{exitCode: exitValidation, err: failed}
+	}
+
+	return nil
+}
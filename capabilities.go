@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"reflect"
+	"runtime/debug"
+
+	"github.com/linuxmk/eml-sender/emlparse"
+)
+
+// version of the application, set at build time with -ldflags "-X main.version=1.2.3"
+var version = "dev"
+
+// output formats of -format and input formats of -input
+var (
+	outputFormats = []string{"json", "csv", "plain", "mbox", "address"}
+	inputFormats  = []string{"eml", "jsonl"}
+)
+
+// capabilitiesOutput structure contains what the build supports for -capabilities,
+// so scripts that wrap the application can check it before invoking it
+type capabilitiesOutput struct {
+	Version  string     `json:"version"`
+	Headers  []string   `json:"headers"`
+	Formats  []string   `json:"formats"`
+	Inputs   []string   `json:"inputs"`
+	Fields   []string   `json:"fields"`
+	Warnings []string   `json:"warnings"`
+	Flags    []flagInfo `json:"flags"`
+}
+
+// flagInfo structure describes a single command line option
+type flagInfo struct {
+	Name    string `json:"name"`
+	Default string `json:"default"`
+	Usage   string `json:"usage"`
+}
+
+// find the version of the application, the -ldflags value or the module version of the build
+//
+// Returns the version, dev for a local build
+func appVersion() string {
+	if version != "dev" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return version
+}
+
+// collect the capabilities from the registered options, the output formats, the json keys and the known headers
+//
+// Returns the capabilities of the build
+func capabilities() capabilitiesOutput {
+
+	//any header can be passed with -header, these are the ones with a special meaning
+	headers := append(addressHeaders[:len(addressHeaders):len(addressHeaders)], "Return-Path")
+	for _, header := range addressHeaders {
+		if header != "Reply-To" {
+			headers = append(headers, "Resent-"+header)
+		}
+	}
+
+	flags := []flagInfo{}
+	flag.VisitAll(func(f *flag.Flag) {
+		flags = append(flags, flagInfo{Name: f.Name, Default: f.DefValue, Usage: f.Usage})
+	})
+
+	return capabilitiesOutput{
+		Version:  appVersion(),
+		Headers:  headers,
+		Formats:  outputFormats,
+		Inputs:   inputFormats,
+		Fields:   jsonFieldNames(reflect.TypeOf(jsonOutput{})),
+		Warnings: emlparse.KnownWarnings,
+		Flags:    flags,
+	}
+}
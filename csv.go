@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+)
+
+// csvOutput structure writes the extracted data as csv rows
+// The header row is written once, before the first row, so batch runs share a single header
+type csvOutput struct {
+	w       *csv.Writer
+	fields  []string
+	schema  map[string]string
+	started bool
+}
+
+// create a csv output that writes to w
+// The columns are the -fields, by default display_name, addr_spec and error,
+// the header row uses the key names of the -schema
+// source is the key that traces a row back to its input in a batch run, e.g. filename,
+// it is the first default column, empty for a single email
+//
+// Returns the csv output
+func newCSVOutput(w io.Writer, fields []string, schema map[string]string, source string) *csvOutput {
+	if len(fields) == 0 {
+		fields = []string{"display_name", "addr_spec", "error"}
+		if source != "" {
+			fields = append([]string{source}, fields...)
+		}
+	}
+	return &csvOutput{w: csv.NewWriter(w), fields: fields, schema: schema}
+}
+
+// write one csv row for every json structure
+// Display names with commas or quotes are escaped by the csv writer
+//
+// Returns an error if the rows can not be written
+func (c *csvOutput) write(jsonOut []jsonOutput) error {
+
+	if !c.started {
+		c.started = true
+		header := make([]string, 0, len(c.fields))
+		for _, name := range c.fields {
+			header = append(header, schemaKey(c.schema, name))
+		}
+		if err := c.w.Write(header); err != nil {
+			return err
+		}
+	}
+
+	for _, record := range jsonOut {
+		values := map[string]any{}
+		json.Unmarshal(selectFields(record, c.fields), &values)
+
+		row := make([]string, 0, len(c.fields))
+		for _, name := range c.fields {
+			row = append(row, csvValue(values[name]))
+		}
+		if err := c.w.Write(row); err != nil {
+			return err
+		}
+	}
+
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// convert a json value to the text of a csv cell
+//
+// Returns the string itself, an empty string for null or the json text of any other value
+func csvValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		text, _ := json.Marshal(v)
+		return string(text)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCSVOutputSource(t *testing.T) {
+
+	message := "no addr-spec found"
+	records := []jsonOutput{
+		{Filename: "mails/a.eml", Name: "Peter Pan", Email: "peter@pan.com"},
+		{Filename: "mails/b.eml", Error: &message},
+	}
+
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{source: "", want: "display_name,addr_spec,error\nPeter Pan,peter@pan.com,\n,,no addr-spec found\n"},
+		{source: "filename", want: "filename,display_name,addr_spec,error\nmails/a.eml,Peter Pan,peter@pan.com,\nmails/b.eml,,,no addr-spec found\n"},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := newCSVOutput(&buf, nil, nil, tt.source).write(records); err != nil {
+			t.Fatalf("write unexpected error: %v", err)
+		}
+		if buf.String() != tt.want {
+			t.Errorf("csv with source %q = %q, want %q", tt.source, buf.String(), tt.want)
+		}
+	}
+}
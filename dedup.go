@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+
+	"github.com/linuxmk/eml-sender/emlparse"
+)
+
+// dedupOutput structure collects the records of a batch run for -dedup
+// so that every address is output once, with the number of times it occurred
+// With -sort the records are collected to be sorted, without -dedup every record is kept
+type dedupOutput struct {
+	records []jsonOutput
+	index   map[string]int //index of the record of every normalized address
+	keepAll bool           //-sort without -dedup, the records are not merged
+	sorter  *recordSorter
+}
+
+// add the records of a file, message or test string
+// A record with an address already seen only increases the count of the first record,
+// records with an error are always kept
+//
+// Return void
+func (d *dedupOutput) add(jsonOut []jsonOutput) {
+
+	if d.index == nil {
+		d.index = map[string]int{}
+	}
+
+	for _, record := range jsonOut {
+		if record.Error != nil || d.keepAll {
+			d.records = append(d.records, record)
+			continue
+		}
+
+		//the key is the normalized address, not the display name
+		key := emlparse.NormalizeAddress(stripBrackets(record.Email))
+		if i, seen := d.index[key]; seen {
+			d.records[i].Count++
+			continue
+		}
+
+		record.Count = 1
+		d.index[key] = len(d.records)
+		d.records = append(d.records, record)
+	}
+}
+
+// output the records of a batch run and the -stats summary
+// With -dedup the collected records are output once, in the order they first occurred
+// or in the order of -sort
+//
+// Return void
+func finishBatch(opts outputOptions) {
+
+	if opts.dedup != nil {
+		records := opts.dedup.records
+		if opts.dedup.sorter != nil {
+			opts.dedup.sorter.sort(records)
+		}
+		//-first was applied to every file or message when the records were collected,
+		//the collected records are written as an array and not as a single object
+		opts.first = false
+		writeRecords(records, opts)
+	}
+
+	opts.stats.print(os.Stderr)
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/linuxmk/eml-sender/emlparse"
+)
+
+// onErrorPolicies are the values of -on-error, what a batch run does with a file that can not be read
+var onErrorPolicies = []string{"skip", "record", "abort"}
+
+// parse the header of every .eml file in a directory
+// and output one record with the filename for every file, sorted by filename
+// The files are parsed concurrently by a pool of workers goroutines
+// An error in a single file does not stop the run, it is reported in the record of the file
+// A file that can not be read is handled by -on-error
+// With -dry-run only the paths of the files are printed, one per line, and nothing is parsed
+//
+// Returns an error if the directory can not be read or -on-error=abort stopped the run,
+// or errCheckFailed if at least one file fails in -check mode
+func doDirectory(parser *emlparse.Parser, dirname string, headers []string, extensions []string, recursive bool, workers int, opts outputOptions) error {
+
+	files, err := listEmlFiles(dirname, extensions, recursive)
+	if err != nil {
+		return err
+	}
+
+	if opts.dryRun {
+		printPaths(files, opts)
+		return nil
+	}
+
+	return parseFiles(parser, files, headers, workers, opts)
+}
+
+// parse the header of every file of a list and output one record with the filename for every file, in the order of the list
+// The files are parsed concurrently by a pool of workers goroutines
+// An error in a single file is reported in the record of the file
+// A file that can not be read(e.g. it does not exist or permission denied) is handled by -on-error:
+// skip outputs no record, record outputs the error record and abort stops the run at the file
+// In -check mode nothing is output, the errors are printed to stderr with the filename
+//
+// Returns the error of the file that stopped the run with -on-error=abort
+// or errCheckFailed if at least one file fails in -check mode
+func parseFiles(parser *emlparse.Parser, files []string, headers []string, workers int, opts outputOptions) error {
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	//every worker writes only the result of its own file index, so no locking is needed
+	results := make([][]jsonOutput, len(files))
+	errs := make([]error, len(files))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				senderInfo, data, err := parseFile(parser, files[i], headers, opts.hash)
+
+				jsonOut := buildJSONOutput(senderInfo, err, opts)
+				annotateHeader(jsonOut, headers, data, opts)
+				for j := range jsonOut {
+					jsonOut[j].Filename = files[i]
+				}
+				results[i] = jsonOut
+				errs[i] = err
+			}
+		}()
+	}
+
+	for i := range files {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed := false
+	for i, jsonOut := range results {
+		opts.stats.add(errs[i])
+		if isIOError(errs[i]) {
+			switch opts.onError {
+			case "skip":
+				continue
+			case "abort":
+				return errs[i]
+			}
+		}
+
+		if opts.check {
+			if errs[i] != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", files[i], errs[i])
+				failed = true
+			}
+			continue
+		}
+
+		printOutput(jsonOut, opts)
+	}
+
+	if failed {
+		return errCheckFailed
+	}
+
+	return nil
+}
+
+// find the email files(.eml and .eml.gz by default) in a directory, with recursive also in all of its subdirectories
+//
+// Returns an error if the directory can not be read or the list of file paths in lexical order
+func listEmlFiles(dirname string, extensions []string, recursive bool) ([]string, error) {
+
+	files := []string{}
+
+	err := filepath.WalkDir(dirname, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path != dirname && !recursive {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if isEmlFile(d.Name(), extensions) {
+			files = append(files, path)
+		}
+		return nil
+	})
+
+	sort.Strings(files)
+
+	return files, err
+}
+
+// parse the comma separated list of -extensions, a missing leading dot is added
+//
+// Returns the list of lowercase extensions, e.g. .eml and .email
+func parseExtensions(list string) []string {
+
+	extensions := []string{}
+	for _, ext := range strings.Split(list, ",") {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		extensions = append(extensions, ext)
+	}
+
+	return extensions
+}
+
+// check if a filename ends with one of the email extensions, also gzip compressed(e.g. .eml.gz)
+// The extension is compared case-insensitive, notes.eml.txt does not match .eml
+//
+// Returns true for an email file
+func isEmlFile(filename string, extensions []string) bool {
+
+	name := strings.TrimSuffix(strings.ToLower(filename), ".gz")
+	for _, ext := range extensions {
+		if strings.HasSuffix(name, ext) && len(name) > len(ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// print the paths of the files that would be parsed for -dry-run, one per line
+//
+// Return void
+func printPaths(files []string, opts outputOptions) {
+	for _, file := range files {
+		fmt.Fprintln(opts.out, file)
+	}
+}
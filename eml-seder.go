@@ -2,364 +2,1129 @@ package main
 
 import (
 	"bufio"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"net/mail"
 	"os"
 	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/linuxmk/eml-sender/emlparse"
 )
 
 // jsonOutput structure contains the data extracted from a
 // "From:" string in an email data
 // It contains the display name, email address and error state
+// Error is a pointer so that a successful parse is written as a real json null
+// Position is the byte offset of the problem in the header value, if the error has one
 type jsonOutput struct {
-	Name  string `json:"display_name"`
-	Email string `json:"addr_spec"`
-	Error string `json:"error"`
+	Filename   string          `json:"filename,omitempty"`
+	FileHash   string          `json:"file_sha256,omitempty"`
+	Message    int             `json:"message,omitempty"`
+	Source     string          `json:"source_header,omitempty"`
+	Raw        string          `json:"raw_from,omitempty"`
+	Name       string          `json:"display_name"`
+	Email      string          `json:"addr_spec"`
+	EmailASCII string          `json:"addr_spec_ascii,omitempty"`
+	EmailNorm  string          `json:"addr_spec_normalized,omitempty"`
+	LocalPart  string          `json:"local_part,omitempty"`
+	Domain     string          `json:"domain,omitempty"`
+	Group      string          `json:"group,omitempty"`
+	Homograph  string          `json:"homograph_warning,omitempty"`
+	Comment    string          `json:"comment,omitempty"`
+	CommentNm  string          `json:"comment_name,omitempty"`
+	Route      bool            `json:"obsolete_route,omitempty"`
+	Mailto     bool            `json:"mailto,omitempty"`
+	Cleaned    bool            `json:"cleaned,omitempty"`
+	NullPath   bool            `json:"null_return_path,omitempty"`
+	Empty      bool            `json:"empty_header,omitempty"`
+	Date       json.RawMessage `json:"date,omitempty"`
+	Subject    string          `json:"subject,omitempty"`
+	MatchRule  string          `json:"match_rule,omitempty"`
+	Count      int             `json:"count,omitempty"`
+	*validityOutput
+	Position *int    `json:"error_position,omitempty"`
+	Error    *string `json:"error"`
+}
+
+// headerData structure contains the values located in the header of an email
+type headerData struct {
+	value   string //value of the parsed address header
+	source  string //name of the parsed address header
+	date    string //value of the Date: header
+	subject string //value of the Subject: header
+	empty   bool   //the header is present, but its value is empty(e.g. an empty Bcc:)
+	hash    string //SHA-256 of the whole email, only with -hash
+}
+
+// exit status returned to the OS
+const (
+	exitOK            = 0 // success
+	exitIOError       = 1 // the file can not be opened or read
+	exitHeaderMissing = 2 // the header is not found in the email
+	exitValidation    = 3 // the header value does not pass the validation
+	exitCheckFailed   = 1 // -check: at least one address does not pass the validation
+	exitUsage         = 4 // the options are invalid, e.g. an unknown -format or -equal without two addresses
+)
+
+// maximum length of a single line read from a file, longer than the 64KB default of bufio.Scanner
+const maxLineLength = 1024 * 1024
+
+// errLineTooLong is returned when a line is longer than maxLineLength
+var errLineTooLong = errors.New("header line too long")
+
+// errCheckFailed is returned by doCustomFileTests in -check mode when at least one line fails
+var errCheckFailed = errors.New("check failed")
+
+// outputFile is the file of -out, it is closed by exit before the application exits
+var outputFile *os.File
+
+// parseError structure contains an error from parsing an email
+// together with the exit status that it maps to
+type parseError struct {
+	exitCode int
+	err      error
+}
+
+func (e *parseError) Error() string {
+	return e.err.Error()
+}
+
+func (e *parseError) Unwrap() error {
+	return e.err
+}
+
+// map an error to the exit status returned to the OS
+//
+// Returns the exit status of a *parseError or exitIOError for any other error
+func exitCode(err error) int {
+	var perr *parseError
+	if errors.As(err, &perr) {
+		return perr.exitCode
+	}
+	return exitIOError
+}
+
+// check if an error is a file I/O error, e.g. a file that does not exist or can not be read,
+// and not an error of the parsed header
+//
+// Returns true for an I/O error
+func isIOError(err error) bool {
+	var perr *parseError
+	return errors.As(err, &perr) && perr.exitCode == exitIOError
+}
+
+// outputOptions structure contains the command line options
+// that control how the extracted data is written
+type outputOptions struct {
+	indent    string //indentation of the json output, empty for compact json
+	ndjson    bool
+	check     bool
+	normalize bool
+	first     bool
+	raw       bool
+	debug     bool
+	date      bool
+	subject   bool
+	validity  bool
+	dryRun    bool
+	splitAddr bool
+	brackets  bool //keep the angle brackets of the addr_spec
+	hash      bool
+	onError   string //skip, record or abort for a file that can not be read in a batch run
+	out       io.Writer
+	csv       *csvOutput
+	human     *humanOutput
+	plain     *plainOutput
+	fields    []string
+	schema    map[string]string
+	stats     *batchStats
+	dedup     *dedupOutput
 }
 
 // start of the application
 //
-// Returns exit status to the OS
+// Returns exit status to the OS:
+// 0 for success, 1 for file I/O errors, 2 if the header is not found, 3 for validation errors, 4 for invalid options
 func main() {
-	if len(os.Args) != 2 {
-		fmt.Printf("Usage: %s file.eml\n", os.Args[0])
-		fmt.Printf("Usage: %s filename <for custom create test strings in a file>\n", os.Args[0])
-		os.Exit(0)
+	header := flag.String("header", "From", "name of the header to extract the addresses from, e.g. To or Cc")
+	rfc5321 := flag.Bool("rfc5321", false, "reject addresses over the SMTP length limits of RFC 5321(local-part 64, domain 255, addr-spec 254 octets)")
+	showVersion := flag.Bool("version", false, "print the version and exit")
+	showCapabilities := flag.Bool("capabilities", false, "print the supported headers, formats, json keys and options as json and exit")
+	werror := flag.Bool("Werror", false, "treat the warnings of an address as errors, see -list-warnings")
+	listWarnings := flag.Bool("list-warnings", false, "print the warnings that -validity reports and -Werror rejects")
+	normalizePunct := flag.Bool("normalize-punct", false, "replace smart quotes, dashes and other Windows-1252 punctuation in display names with ASCII")
+	nfc := flag.Bool("nfc", false, "apply the Unicode normalization form C(NFC) to display names")
+	homograph := flag.Bool("homograph", false, "add homograph_warning for domains with mixed scripts or characters that look like Latin ones")
+	noBareName := flag.Bool("no-bare-name", false, "do not accept a display name and address without angle brackets(John Doe jdoe@x.com)")
+	strictTLD := flag.Bool("strict-tld", false, "reject addresses with a top level domain that is not in the bundled Public Suffix List")
+	idn := flag.Bool("idn", false, "add the punycode(ASCII) form of internationalized domains as addr_spec_ascii")
+	compact := flag.Bool("compact", false, "print every json record on a single line")
+	indent := flag.String("indent", "2", "indentation of the json output, a number of spaces or tab")
+	ndjson := flag.Bool("ndjson", false, "print newline delimited json, one object for every address")
+	comments := flag.Bool("comments", false, "add the text of the comments as comment, a trailing comment is used as missing display name")
+	check := flag.Bool("check", false, "only validate, print nothing on success and the error to stderr on failure")
+	hashFile := flag.Bool("hash", false, "add file_sha256, the SHA-256 of the whole email file(decompressed for .eml.gz)")
+	keepBrackets := flag.Bool("keep-brackets", false, "keep the angle brackets of an addr_spec written in them, e.g. <a@x.com>")
+	splitAddr := flag.Bool("split-addr", false, "add the local-part and the domain of the addr_spec as local_part and domain")
+	normalize := flag.Bool("normalize", false, "add the addr_spec with lowercase domain as addr_spec_normalized")
+	recursive := flag.Bool("recursive", false, "also parse the .eml files in the subdirectories of a directory")
+	extensions := flag.String("extensions", ".eml", "comma separated list of the email file extensions, e.g. .eml,.email,.msg-eml")
+	onError := flag.String("on-error", "record", "what a directory or -manifest run does with a file that can not be read: skip it, record the error or abort the run")
+	workers := flag.Int("workers", runtime.NumCPU(), "number of files of a directory parsed concurrently")
+	format := flag.String("format", "json", "output format: json, csv or plain(one addr_spec per line), mbox to parse every message of an mbox file, or address to read name<TAB>email lines from a file and print formatted From: values")
+	count := flag.Bool("count", false, "only print the number of addr-specs in the header, several addresses are not an error")
+	first := flag.Bool("first", false, "print only the first mailbox of the header as a json object")
+	all := flag.Bool("all", true, "print every mailbox of the header as a json array")
+	resent := flag.Bool("resent", false, "parse the topmost(most recent) Resent- variant of the header, e.g. Resent-From:, if the message was resent")
+	fallback := flag.Bool("fallback", false, "if the header is missing try the Sender: and then the Reply-To: header")
+	raw := flag.Bool("raw", false, "add the original header value, before any cleaning, as raw_from")
+	extraPattern := flag.String("extra-pattern", "", "regex with the named groups display_name and addr_spec, tried when the built-in patterns do not match")
+	maxLength := flag.Int("max-length", emlparse.DefaultMaxLength, "maximum length of a single address in bytes, longer addresses are rejected")
+	date := flag.Bool("date", false, "add the Date: header as date, with the raw value and the parsed time")
+	subject := flag.Bool("subject", false, "add the Subject: header as subject, with the encoded-words decoded")
+	validity := flag.Bool("validity", false, "add validity(valid, valid-with-warnings or invalid) and the list of warnings")
+	stats := flag.Bool("stats", false, "print a summary of a directory, mbox file or test file run to stderr")
+	quiet := flag.Bool("quiet", false, "do not print the -stats summary")
+	debug := flag.Bool("debug", false, "print to stderr which pattern matched every address and add it as match_rule")
+	equal := flag.Bool("equal", false, "compare two addresses given as arguments and print if they are the same mailbox")
+	ignoreDots := flag.Bool("ignore-dots", false, "with -equal ignore the dots in the local-part, like Gmail does")
+	from := flag.String("from", "", "parse the address list given as the value, instead of a file")
+	human := flag.Bool("human", false, "print every address in a readable form instead of json, colored when the output is a terminal")
+	schema := flag.String("schema", "default", "names of the json keys: default(display_name, addr_spec) or short(name, email)")
+	fields := flag.String("fields", "", "comma separated list of the json keys to output, in the given order, e.g. addr_spec,display_name")
+	dedup := flag.Bool("dedup", false, "output every address of a batch run once, with the number of times it occurred as count")
+	sortBy := flag.String("sort", "", "sort the records of a batch run by name(display name) or addr(addr_spec), records with an error last")
+	locale := flag.String("locale", "und", "language of the -sort collation, e.g. de or sv, und for the root collation")
+	allHeaders := flag.Bool("all-headers", false, "parse every address header(From, Sender, Reply-To, To, Cc, Bcc) of an email, keyed by the header name")
+	dryRun := flag.Bool("dry-run", false, "only print the files a directory or -manifest run would parse, one per line")
+	input := flag.String("input", "eml", "format of the input: eml(an email, a directory or a test file) or jsonl(one {\"from\": \"...\"} object per line)")
+	manifest := flag.String("manifest", "", "parse every .eml file listed in a file, one path per line")
+	outFile := flag.String("out", "", "write the results to a file instead of stdout, the file is truncated")
+	appendOut := flag.Bool("append", false, "with -out append the results to the file instead of truncating it")
+	flag.Usage = usage
+
+	//an unknown option or a malformed value is a usage error, the exit status 2 of flag.ExitOnError is the missing header
+	flag.CommandLine.Init(os.Args[0], flag.ContinueOnError)
+	if err := flag.CommandLine.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(exitOK)
+		}
+		os.Exit(exitUsage)
+	}
+
+	parser := &emlparse.Parser{StrictTLD: *strictTLD, IDN: *idn, Comments: *comments, MaxLength: *maxLength, RFC5321: *rfc5321, NoBareName: *noBareName, WarningsAsErrors: *werror, NormalizePunct: *normalizePunct, NFC: *nfc, Homograph: *homograph}
+	if *extraPattern != "" {
+		re, err := compileExtraPattern(*extraPattern)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitUsage)
+		}
+		parser.ExtraPattern = re
+	}
+	opts := outputOptions{ndjson: *ndjson, check: *check, normalize: *normalize, first: *first || !*all, raw: *raw, debug: *debug, date: *date, subject: *subject, validity: *validity, dryRun: *dryRun, splitAddr: *splitAddr, brackets: *keepBrackets, hash: *hashFile, onError: *onError, out: os.Stdout}
+
+	if !*compact {
+		value, err := parseIndent(*indent)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitUsage)
+		}
+		opts.indent = value
+	}
+
+	keys, err := parseSchema(*schema)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(exitUsage)
+	}
+	opts.schema = keys
+
+	if *fields != "" {
+		list, err := parseFields(*fields, opts.schema)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitUsage)
+		}
+		opts.fields = list
+	}
+
+	if *outFile != "" {
+		fd, err := openOutput(*outFile, *appendOut)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(exitIOError)
+		}
+		outputFile = fd
+		defer func() {
+			if code := closeOutput(outputFile, exitOK); code != exitOK {
+				os.Exit(code)
+			}
+		}()
+		opts.out = fd
+	}
+	if *human {
+		opts.human = newHumanOutput(opts.out)
+	}
+
+	headers := []string{*header}
+	emlExtensions := parseExtensions(*extensions)
+	//the topmost Resent- block is the most recent resend(RFC 5322 3.6.6)
+	if *resent && !strings.HasPrefix(strings.ToLower(*header), "resent-") {
+		headers = []string{"Resent-" + *header, *header}
+	}
+	if *fallback {
+		headers = append(headers, "Sender", "Reply-To")
+	}
+
+	if *showVersion {
+		fmt.Fprintln(opts.out, appVersion())
+		return
+	}
+
+	//Describe the build for the scripts that wrap the application
+	if *showCapabilities {
+		fmt.Fprintf(opts.out, "%s\n", createJSONOutput(capabilities(), opts.indent))
+		return
+	}
+
+	//List the warnings that are promoted to errors with -Werror
+	if *listWarnings {
+		for _, warning := range emlparse.KnownWarnings {
+			fmt.Fprintln(opts.out, warning)
+		}
+		return
+	}
+
+	//Compare two addresses given on the command line
+	if *equal {
+		if flag.NArg() != 2 {
+			fmt.Fprintf(os.Stderr, "-equal needs two addresses, got %d\n", flag.NArg())
+			flag.Usage()
+			exit(exitUsage)
+		}
+		result, err := compareAddresses(parser, flag.Arg(0), flag.Arg(1), *ignoreDots)
+		fmt.Fprintf(opts.out, "%s\n", createJSONOutput(result, opts.indent))
+		if err != nil {
+			exit(exitValidation)
+		}
+		return
+	}
+
+	//-count only counts the addresses of a single email or stdin, every other mode would print json without the count
+	if *count {
+		info, err := os.Stat(flag.Arg(0))
+		isDir := err == nil && info.IsDir()
+		singleEmail := flag.NArg() == 1 && !isDir && (flag.Arg(0) == "-" || isEmlFile(flag.Arg(0), emlExtensions))
+		if *from != "" || *manifest != "" || *allHeaders || *input == "jsonl" || *format == "mbox" || !singleEmail {
+			fmt.Fprintln(os.Stderr, "-count needs a single email file or - for stdin")
+			flag.Usage()
+			exit(exitUsage)
+		}
+	}
+
+	switch *format {
+	case "json":
+	case "csv":
+		//the rows of a directory or -manifest run start with the filename
+		source := ""
+		if info, err := os.Stat(flag.Arg(0)); *manifest != "" || (err == nil && info.IsDir()) {
+			source = "filename"
+		}
+		opts.csv = newCSVOutput(opts.out, opts.fields, opts.schema, source)
+	case "plain":
+		opts.plain = &plainOutput{w: opts.out, errOut: os.Stderr}
+	case "mbox":
+	case "address":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown -format %q, use %s\n", *format, strings.Join(outputFormats, ", "))
+		exit(exitUsage)
+	}
+
+	//Parse the value given on the command line, e.g. -from "Name <x@y.com>"
+	if *from != "" {
+		err := doFromString(parser, *from, opts)
+		if err != nil {
+			if opts.check {
+				exit(exitCheckFailed)
+			}
+			exit(exitValidation)
+		}
+		return
+	}
+
+	if flag.NArg() != 1 && *manifest == "" {
+		flag.Usage()
+		exit(exitOK)
+	}
+
+	if !slices.Contains(onErrorPolicies, *onError) {
+		fmt.Fprintf(os.Stderr, "unknown -on-error %q, use %s\n", *onError, strings.Join(onErrorPolicies, ", "))
+		exit(exitUsage)
+	}
+
+	if !slices.Contains(inputFormats, *input) {
+		fmt.Fprintf(os.Stderr, "unknown -input %q, use %s\n", *input, strings.Join(inputFormats, ", "))
+		exit(exitUsage)
+	}
+
+	//Run the opposite direction, build the From: values from names and emails
+	if *format == "address" {
+		err := doFormatAddresses(flag.Arg(0), opts.out)
+		if err != nil {
+			exit(exitIOError)
+		}
+		return
+	}
+
+	if *stats && !*quiet {
+		opts.stats = &batchStats{}
+	}
+	if *dedup || *sortBy != "" {
+		opts.dedup = &dedupOutput{keepAll: !*dedup}
+	}
+	if *sortBy != "" {
+		sorter, err := newRecordSorter(*sortBy, *locale)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitUsage)
+		}
+		opts.dedup.sorter = sorter
+	}
+
+	//Run against all files listed in a manifest file, one record for every file
+	if *manifest != "" {
+		err := doManifest(parser, *manifest, headers, *workers, opts)
+		finishBatch(opts)
+		if errors.Is(err, errCheckFailed) {
+			exit(exitCheckFailed)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitIOError)
+		}
+		return
 	}
 
-	//Run against a specific file containg all data from the header
-	if strings.Contains(os.Args[1], ".eml") {
-		senderInfo, err := parseFile(os.Args[1])
+	//Run against all address headers of a single email
+	if *allHeaders {
+		err := doAllHeaders(parser, flag.Arg(0), opts)
+		if errors.Is(err, errCheckFailed) {
+			exit(exitCheckFailed)
+		}
 		if err != nil {
-			os.Exit(1)
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitCode(err))
 		}
+		return
+	}
 
-		//display the data on the stdout - console in json format
-		displayData(senderInfo, err)
+	//Run against the "from" field of every line of a json lines file
+	if *input == "jsonl" {
+		err := doJSONLines(parser, flag.Arg(0), opts)
+		finishBatch(opts)
+		if errors.Is(err, errCheckFailed) {
+			exit(exitCheckFailed)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitIOError)
+		}
+		return
+	}
+
+	//Run against every message of an mbox file
+	if *format == "mbox" {
+		err := doMbox(parser, flag.Arg(0), headers, opts)
+		finishBatch(opts)
+		if errors.Is(err, errCheckFailed) {
+			exit(exitCheckFailed)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitIOError)
+		}
+		return
+	}
+
+	//Run against all .eml files in a directory, one record for every file
+	if info, err := os.Stat(flag.Arg(0)); err == nil && info.IsDir() {
+		err := doDirectory(parser, flag.Arg(0), headers, emlExtensions, *recursive, *workers, opts)
+		finishBatch(opts)
+		if errors.Is(err, errCheckFailed) {
+			exit(exitCheckFailed)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitIOError)
+		}
+		return
+	}
+
+	//Run against a specific file(or stdin) containg all data from the header
+	if flag.Arg(0) == "-" || isEmlFile(flag.Arg(0), emlExtensions) {
+		if *count {
+			data, err := readHeader(flag.Arg(0), headers, false)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(exitCode(err))
+			}
+			fmt.Fprintln(opts.out, emlparse.CountAddresses(data.value))
+			return
+		}
+
+		if opts.check {
+			if _, _, err := parseFile(parser, flag.Arg(0), headers, opts.hash); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				exit(exitCheckFailed)
+			}
+			exit(exitOK)
+		}
+
+		err := doEmailFile(parser, flag.Arg(0), headers, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitCode(err))
+		}
 	} else {
 		//run tests from a external file, where
 		//everyline is a specific "Form:" string
-		doCustomFileTests(os.Args[1])
+		err := doCustomFileTests(parser, flag.Arg(0), opts)
+		finishBatch(opts)
+		if errors.Is(err, errCheckFailed) {
+			exit(exitCheckFailed)
+		}
+		if err != nil {
+			exit(exitIOError)
+		}
 	}
 }
 
-// Parse the filename that is sent as a parameter to the application
+// print the usage lines of every mode and the list of options, for -h, -help and a missing argument
 //
-// Returns an error if filename can not be opened, located the "Fron:" string and extract the email info
-// or a valid map of display name and/or email
-func parseFile(filename string) (map[string]string, error) {
+// Return void
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "Usage: %s [-header=From] file.eml\n", os.Args[0])
+	fmt.Fprintf(w, "Usage: %s [-header=From] - <read the email from stdin>\n", os.Args[0])
+	fmt.Fprintf(w, "Usage: %s [-header=From] [-recursive] directory <parse all .eml files in a directory>\n", os.Args[0])
+	fmt.Fprintf(w, "Usage: %s filename <for custom create test strings in a file>\n", os.Args[0])
+	fmt.Fprintf(w, "Usage: %s -format=mbox [-header=From] file.mbox <parse every message of an mbox file>\n", os.Args[0])
+	fmt.Fprintf(w, "Usage: %s -format=address filename <format name<TAB>email lines as From: values>\n", os.Args[0])
+	fmt.Fprintf(w, "Usage: %s [-header=From] -manifest files.txt <parse all .eml files listed in a file>\n", os.Args[0])
+	fmt.Fprintf(w, "Usage: %s -from \"Name <x@y.com>\" <parse the address list given on the command line>\n", os.Args[0])
+	fmt.Fprintf(w, "Usage: %s -equal [-ignore-dots] address address <compare two addresses>\n", os.Args[0])
+	fmt.Fprintf(w, "Usage: %s -all-headers file.eml <parse every address header of an email>\n", os.Args[0])
+	fmt.Fprintf(w, "Usage: %s -input=jsonl file.jsonl <parse the \"from\" field of every json line>\n", os.Args[0])
+	fmt.Fprintln(w, "\nOptions:")
+	flag.PrintDefaults()
+}
 
-	//Open the file that is passed from the command line as an argument and check it for error
-	fd, err := os.Open(filename)
-	if err != nil {
-		fmt.Printf(err.Error())
-		return nil, err
+// convert the value of -indent to the indentation of the json output
+// The value is a number of spaces(0 is compact json) or tab
+//
+// Returns an error if the value is not a number or tab, or the indentation string
+func parseIndent(value string) (string, error) {
+
+	if value == "tab" {
+		return "\t", nil
 	}
 
-	str, err := locateString(fd, "From:")
-	if err != nil {
-		fmt.Println(err)
-		return nil, err
+	spaces, err := strconv.Atoi(value)
+	if err != nil || spaces < 0 {
+		return "", fmt.Errorf("invalid -indent %q, use a number of spaces or tab", value)
 	}
 
-	str, err = checkForErrors(str)
-	if err != nil {
-		return nil, err
+	return strings.Repeat(" ", spaces), nil
+}
+
+// open the -out file for writing the results, it is created if it does not exist
+// The file is truncated, with appendMode the results are appended to it
+//
+// Returns an error if the file can not be opened or the opened file
+func openOutput(filename string, appendMode bool) (*os.File, error) {
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendMode {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
 	}
 
-	//extract the data from the "From:" string
-	senderInfo, err := extractEmailInfo(str)
+	fd, err := os.OpenFile(filename, flags, 0644)
 	if err != nil {
-		fmt.Println(err)
-		return nil, err
+		return nil, fmt.Errorf("can not open the output file: %w", err)
 	}
 
-	//close the opened file and check for error
-	err = fd.Close()
+	return fd, nil
+}
+
+// exit the application with the exit status code, the output file of -out is closed first
+// because os.Exit skips the deferred calls
+//
+// Return void, it does not return
+func exit(code int) {
+	os.Exit(closeOutput(outputFile, code))
+}
+
+// close the output file of -out, a write error that is only reported by Close is printed to stderr
+//
+// Returns the exit status, exitIOError instead of exitOK if the file can not be closed
+func closeOutput(fd *os.File, code int) int {
+
+	if fd == nil {
+		return code
+	}
+
+	if err := fd.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "can not close the output file: %v\n", err)
+		if code == exitOK {
+			return exitIOError
+		}
+	}
+
+	return code
+}
+
+// compile the regex of -extra-pattern and check that it has the named group addr_spec
+//
+// Returns an error if the regex is invalid or the compiled regex
+func compileExtraPattern(pattern string) (*regexp.Regexp, error) {
+
+	re, err := regexp.Compile(pattern)
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return nil, fmt.Errorf("invalid -extra-pattern: %w", err)
 	}
 
-	return senderInfo, nil
+	if re.SubexpIndex("addr_spec") < 0 {
+		return nil, fmt.Errorf("invalid -extra-pattern: the named group (?P<addr_spec>...) is missing")
+	}
+
+	return re, nil
 }
 
-// output the json data extracted from the email to stdout
+// Parse the filename that is sent as a parameter to the application
+// The filename "-" reads the email from stdin, a .eml.gz file is decompressed while reading
+// The headers are tried in order, the first one present in the email is parsed
+// With hash the whole email is read to compute its SHA-256
 //
-// Return void/noting
-func displayData(senderInfo map[string]string, err error) {
+// Returns a *parseError if filename can not be opened, located the header (e.g. "From:") string and extract the email info
+// or a valid list of display name and/or email, one for every mailbox in the header,
+// and the values located in the header of the email
+func parseFile(parser *emlparse.Parser, filename string, headers []string, hash bool) ([]emlparse.Address, headerData, error) {
 
-	jsonOut := jsonOutput{Error: "null"}
-	jsonOut.Name = senderInfo["display_name"]
-	jsonOut.Email = senderInfo["addr_spec"]
+	data, err := readHeader(filename, headers, hash)
 	if err != nil {
-		jsonOut.Error = err.Error()
-	} else {
-		jsonOut.Error = "null"
+		return nil, data, err
+	}
+
+	//extract the data from the header string
+	senderInfo, err := parseHeaderValue(parser, data)
+	if err != nil {
+		return nil, data, &parseError{exitCode: exitValidation, err: err}
 	}
-	fmt.Printf(" %s\n", createJSONOutput(jsonOut))
+
+	return senderInfo, data, nil
 }
 
-// locate a string("From:") in a string
+// parse the value of the located header, the Return-Path: header has its own rules
+// because of the null return path <> of bounce messages
+// An empty header gives a single empty address, so it is output with empty_header
 //
-// Return nil if the string is not locate, or the line where the search string is found
-func locateString(fd *os.File, str string) (string, error) {
+// Returns an error if the value does not pass the validation or the list of addresses
+func parseHeaderValue(parser *emlparse.Parser, data headerData) ([]emlparse.Address, error) {
 
-	scanner := bufio.NewScanner(fd)
-	for scanner.Scan() {
-		line := scanner.Text()
+	if data.empty {
+		return []emlparse.Address{{}}, nil
+	}
 
-		//line == "" handles both cases transparently because bufio.Scanner automatically strips \r\n(Windows) or \n(Linux/macOS)
-		if line == "" {
-			break
-		} else if strings.HasPrefix(strings.ToLower(line), strings.ToLower(str)) {
-			return strings.TrimSpace(line[len("from:"):]), nil // "null"
+	if strings.EqualFold(data.source, "Return-Path") {
+		address, err := parser.ParseReturnPath(data.value)
+		if err != nil {
+			return nil, err
 		}
+		return []emlparse.Address{address}, nil
 	}
 
-	return "", fmt.Errorf("\"From\" header missing or value is empty")
+	return parser.ParseAddressList(data.value)
 }
 
-func readTestStrings(filename string) ([]string, error) {
+// parse the header of a single email(or stdin with "-") and output the records of its addresses
+// The email is counted for -stats like a batch of one file, and the -dedup and -sort stage is finished
+//
+// Returns a *parseError if the email can not be read or the header does not pass the validation
+func doEmailFile(parser *emlparse.Parser, filename string, headers []string, opts outputOptions) error {
 
-	lines := []string{}
+	senderInfo, data, err := parseFile(parser, filename, headers, opts.hash)
+	opts.stats.add(err)
+	if err != nil {
+		opts.stats.print(os.Stderr)
+		return err
+	}
 
-	fd, err := os.Open(filename)
+	//display the data on the stdout - console in json format
+	jsonOut := buildJSONOutput(senderInfo, nil, opts)
+	annotateHeader(jsonOut, headers, data, opts)
+	printOutput(jsonOut, opts)
+	finishBatch(opts)
+
+	return nil
+}
+
+// Read the value of a header from the filename that is sent as a parameter to the application
+// The filename "-" reads the email from stdin, a .eml.gz file is decompressed while reading
+// The headers are tried in order, the first one present in the email is returned
+// With hash the email is hashed while it is scanned, and the rest after the header section is read
+// to complete the SHA-256, so the file is read only once
+//
+// Returns a *parseError if filename can not be opened or none of the header strings is located
+// or the values located in the header of the email
+func readHeader(filename string, headers []string, hash bool) (headerData, error) {
+
+	r, closer, err := openInput(filename)
 	if err != nil {
-		fmt.Printf(err.Error())
-		return nil, err
+		return headerData{}, err
 	}
 
-	scanner := bufio.NewScanner(fd)
-	for scanner.Scan() {
-		line := scanner.Text()
+	hasher := sha256.New()
+	if hash {
+		r = io.TeeReader(r, hasher)
+	}
 
-		//line == "" handles both cases transparently because bufio.Scanner automatically strips \r\n(Windows) or \n(Linux/macOS)
-		if line == "" {
-			break
+	data, err := locateHeader(r, headers)
+	if hash {
+		if _, err := io.Copy(io.Discard, r); err != nil {
+			closer.Close()
+			return data, &parseError{exitCode: exitIOError, err: err}
 		}
-		lines = append(lines, line)
+		data.hash = hex.EncodeToString(hasher.Sum(nil))
+	}
+	if err != nil {
+		closer.Close()
+		return data, err
 	}
 
 	//close the opened file and check for error
-	err = fd.Close()
+	err = closer.Close()
 	if err != nil {
-		fmt.Println(err)
-		os.Exit(1)
+		return headerData{}, &parseError{exitCode: exitIOError, err: err}
 	}
 
-	return lines, nil
+	return data, nil
 }
 
-// run test cases using different combinations of display name and email address
+// Open the filename that is sent as a parameter to the application for reading
+// The filename "-" reads from stdin, a .gz file is decompressed while reading
 //
-// Return void
-func doCustomFileTests(filename string) {
-	var emails, err = readTestStrings(filename)
+// Returns a *parseError if filename can not be opened
+// or the reader and the closer of the opened file
+func openInput(filename string) (io.Reader, io.Closer, error) {
 
-	if err != nil {
-		fmt.Println(err)
+	if filename == "-" {
+		return os.Stdin, io.NopCloser(os.Stdin), nil
 	}
 
-	// test each input string in the array
-	for _, fromStr := range emails {
-		info := make(map[string]string)
+	//Open the file that is passed from the command line as an argument and check it for error
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, &parseError{exitCode: exitIOError, err: err}
+	}
 
-		fromStr, err := checkForErrors(fromStr)
+	if strings.HasSuffix(strings.ToLower(filename), ".gz") {
+		gz, err := gzip.NewReader(fd)
 		if err != nil {
-			info["display_name"] = ""
-			info["addr_spec"] = ""
-			info["error"] = err.Error()
-		} else {
-			info, err = extractEmailInfo(fromStr)
-			info["display_name"] = strings.ReplaceAll(info["display_name"], "<", "")
-			info["addr_spec"] = strings.ReplaceAll(info["addr_spec"], ">", "")
+			fd.Close()
+			return nil, nil, &parseError{exitCode: exitIOError, err: err}
 		}
-		displayData(info, err)
+		return gz, fd, nil
 	}
+
+	return fd, fd, nil
 }
 
-// Extracts the display name and email address from a string
-func extractEmailInfo(input string) (map[string]string, error) {
+// Locate the first present of the headers in the email read from a reader(file or stdin)
+// The Date: and Subject: headers are located together with it
+// A present, but empty Bcc: or Resent-Bcc: header is not missing, RFC 5322 allows it to hide all recipients
+//
+// Returns a *parseError if none of the header strings is located or the email can not be read
+// or the values located in the header of the email, the Date: and Subject: headers are also returned with the error of a missing header
+func locateHeader(r io.Reader, headers []string) (headerData, error) {
+
+	names := append(headers[:len(headers):len(headers)], "Date", "Subject")
+	values, err := emlparse.LocateHeaders(emlparse.DecodeInput(r), names)
+	if err != nil {
+		if errors.Is(err, emlparse.ErrEmptyHeaders) || errors.Is(err, emlparse.ErrEmptyInput) {
+			return headerData{}, &parseError{exitCode: exitHeaderMissing, err: err}
+		}
+		return headerData{}, &parseError{exitCode: exitIOError, err: err}
+	}
+
+	data := headerData{date: values["Date"], subject: values["Subject"]}
+	for _, header := range headers {
+		if values[header] != "" {
+			data.value, data.source = values[header], header
+			return data, nil
+		}
+	}
 
-	retVal := make(map[string]string)
+	for _, header := range headers {
+		if value, present := values[header]; present && value == "" && (strings.EqualFold(header, "Bcc") || strings.EqualFold(header, "Resent-Bcc")) {
+			data.source, data.empty = header, true
+			return data, nil
+		}
+	}
 
-	//First, clean the input by trimming whitespace and special chars
-	input = strings.ReplaceAll(input, "“", `"`)
-	input = strings.ReplaceAll(input, "”", `"`)
-	input = strings.ReplaceAll(input, "\"", ``)
+	//the error is about the primary header, the fallbacks are optional
+	err = fmt.Errorf("%q %w", headers[0], emlparse.ErrHeaderMissing)
+	return data, &parseError{exitCode: exitHeaderMissing, err: err}
+}
 
-	//workhorse of the application
-	//parses the input string extracted from the email
-	retVal = parseDisplayNameAndEmail(input)
-	return retVal, nil
+// add the values located in the header of the email to the json structures
+// The name of the parsed header is added when fallback headers were tried, so it is visible which one was used
+// The original header value is added with -raw, the Date: header with -date and the Subject: header with -subject
+//
+// Return void
+func annotateHeader(jsonOut []jsonOutput, headers []string, data headerData, opts outputOptions) {
+	for i := range jsonOut {
+		jsonOut[i].Empty = data.empty
+		jsonOut[i].FileHash = data.hash
+		if len(headers) >= 2 {
+			jsonOut[i].Source = data.source
+		}
+		if opts.raw {
+			jsonOut[i].Raw = data.value
+		}
+		if opts.date {
+			jsonOut[i].Date = dateField(data.date)
+		}
+		if opts.subject {
+			//the value is already unfolded by LocateHeaders, so encoded-words split over lines are adjacent
+			jsonOut[i].Subject = emlparse.DecodeHeader(data.subject)
+		}
+	}
 }
 
-// remove nested comments in a string if they exits
+// build the value of the json date field from the value of the Date: header
+// The time is parsed from the RFC 5322(RFC 1123Z) date and written in RFC 3339 format
 //
-// Returns a string without comments in "()"
-func removeNestedComments(s string) string {
+// Returns json null for a missing header or a malformed date, otherwise the raw value and the parsed time
+func dateField(value string) json.RawMessage {
 
-	var sb strings.Builder
-	depth := 0
+	t, err := mail.ParseDate(value)
+	if value == "" || err != nil {
+		return json.RawMessage("null")
+	}
 
-	for i := 0; i < len(s); i++ {
-		char := s[i]
+	date := struct {
+		Raw  string `json:"raw"`
+		Time string `json:"time"`
+	}{Raw: value, Time: t.Format(time.RFC3339)}
 
-		if char == '(' {
-			depth++
-		}
+	retVal, _ := json.Marshal(date)
+	return retVal
+}
 
-		if depth == 0 {
-			sb.WriteByte(char)
-		}
+// build the json structures for the data extracted from the email
+//
+// Returns one structure for every mailbox or a single structure with the error
+func buildJSONOutput(senderInfo []emlparse.Address, err error, opts outputOptions) []jsonOutput {
 
-		if char == ')' && depth > 0 {
-			depth--
+	jsonOut := []jsonOutput{}
+	if err != nil {
+		record := jsonOutput{Error: errorField(err), Position: errorPosition(err)}
+		if opts.validity {
+			record.validityOutput = classify(emlparse.Address{}, err)
+		}
+		jsonOut = append(jsonOut, record)
+		if opts.debug && errors.Is(err, emlparse.ErrNoMatch) {
+			fmt.Fprintln(os.Stderr, "debug: no pattern matched")
+		}
+	} else {
+		for _, info := range senderInfo {
+			record := jsonOutput{
+				Name:       info.DisplayName,
+				Email:      info.AddrSpec,
+				EmailASCII: info.AddrSpecASCII,
+				Group:      info.Group,
+				Homograph:  info.HomographWarning,
+				Comment:    info.Comment,
+				CommentNm:  info.CommentName,
+				Route:      info.ObsoleteRoute,
+				Mailto:     info.Mailto,
+				Cleaned:    info.Cleaned,
+				NullPath:   info.NullReturnPath,
+			}
+			if opts.normalize {
+				record.EmailNorm = emlparse.NormalizeAddress(info.AddrSpec)
+			}
+			if opts.splitAddr {
+				record.LocalPart, record.Domain = emlparse.SplitAddress(info.AddrSpec)
+			}
+			if opts.validity {
+				record.validityOutput = classify(info, nil)
+			}
+			if opts.brackets && info.AngleBrackets {
+				record.Email = "<" + record.Email + ">"
+			}
+			if opts.debug {
+				record.MatchRule = info.MatchRule
+				fmt.Fprintf(os.Stderr, "debug: %q: matched %s\n", info.AddrSpec, info.MatchRule)
+			}
+			jsonOut = append(jsonOut, record)
 		}
 	}
 
-	return strings.TrimSpace(sb.String())
+	return jsonOut
 }
 
-// check the "From:" string for validation
-// Also makes some small transformation of the input string
-// validates the from line against :
-// 1. nested <> in addr_spec
-// 2. missing @ domain
-// 3. no addr-spec found
-// 4. RFC 5322 forbids the localpart (what comes before the last @ in addr-spec) from ending in a dot
-// 5. more than one addr-spec given
-// 6. unterminated quoted part
+// remove the angle brackets that -keep-brackets leaves on an addr_spec
 //
-// Returns an error if the validation does not passes
-// or input string with small transformation for following analysis in detection
-func checkForErrors(str string) (string, error) {
+// Returns the addr_spec without the angle brackets
+func stripBrackets(addrSpec string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(addrSpec, "<"), ">")
+}
 
-	str = strings.Trim(str, "\n\r")
+// convert an error to the value of the json error field
+//
+// Returns nil(json null) when there is no error or a pointer to the error message
+func errorField(err error) *string {
+	if err == nil {
+		return nil
+	}
+	message := err.Error()
+	return &message
+}
 
-	brackets := strings.Contains(str, ">>") || strings.Contains(str, "<<")
+// find the byte offset where the parser detected the problem
+//
+// Returns nil if the error has no position
+func errorPosition(err error) *int {
+	var perr *emlparse.PositionError
+	if !errors.As(err, &perr) || perr.Position < 0 {
+		return nil
+	}
+	return &perr.Position
+}
 
-	if brackets {
-		return "", fmt.Errorf("nested < .. > not allowed as part of addr-spec")
+// output the json structures to stdout(or the -out file)
+// as an array or with ndjson as one compact object per line, or as csv rows with -format=csv,
+// or as readable lines with -human, or only the addr_spec of every address with -format=plain
+// With -first only the first structure is written as a single object
+//
+// Return void/noting
+func printOutput(jsonOut []jsonOutput, opts outputOptions) {
+
+	if opts.first && len(jsonOut) > 0 {
+		jsonOut = jsonOut[:1]
 	}
 
-	checkEmailSym := strings.Split(str, "@")
-	if strings.Contains(str, "<") && len(checkEmailSym) == 1 {
-		return "", fmt.Errorf("missing @ domain")
+	if opts.dedup != nil {
+		opts.dedup.add(jsonOut)
+		return
 	}
 
-	if len(checkEmailSym) == 1 {
-		return "", fmt.Errorf("no addr-spec found")
+	writeRecords(jsonOut, opts)
+}
+
+// write the json structures as csv rows, readable lines, addr_specs or json, without the -first and -dedup stage
+// of printOutput, so the records collected by -dedup and -sort are written as they are
+//
+// Return void
+func writeRecords(jsonOut []jsonOutput, opts outputOptions) {
+
+	if opts.csv != nil {
+		if err := opts.csv.write(jsonOut); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitIOError)
+		}
+		return
 	}
 
-	userName, domain := checkEmailSym[0], checkEmailSym[1]
-	if strings.HasPrefix(userName, ".") || strings.HasSuffix(userName, ".") || strings.HasPrefix(domain, ".") {
-		return "", fmt.Errorf("RFC 5322 forbids the localpart (what comes before the last @ in addr-spec) from ending in a dot")
+	if opts.human != nil {
+		if err := opts.human.write(jsonOut); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitIOError)
+		}
+		return
 	}
 
-	emailSplit := strings.Split(str, "\"")
-	if len(emailSplit) == 3 {
-		numEmails := countNoEmails(emailSplit[2])
-		if numEmails > 1 {
-			fmt.Printf("")
-			return "", fmt.Errorf("more than one addr-spec given")
+	if opts.plain != nil {
+		if err := opts.plain.write(jsonOut); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			exit(exitIOError)
 		}
+		return
 	}
 
-	if len(emailSplit) > 1 && strings.Contains(emailSplit[1], "<") && strings.Contains(emailSplit[1], ">") {
-		str = strings.Replace(str, "<", "(", 1)
-		str = strings.Replace(str, ">", ")", 1)
-		str = removeNestedComments(str)
+	records := make([]any, 0, len(jsonOut))
+	for _, record := range jsonOut {
+		records = append(records, outputRecord(record, opts.fields, opts.schema))
 	}
-	noQuotes := 0
-	noQuotes = strings.Count(str, "\"")
 
-	noEscQuotes := strings.Count(str, "\\\"")
-	if noEscQuotes > 0 {
-		if noQuotes%2 == 0 {
-			str = strings.Replace(str, "\\\"", "", noEscQuotes)
+	var err error
+	switch {
+	case opts.ndjson:
+		for _, record := range records {
+			if _, err = fmt.Fprintf(opts.out, "%s\n", createJSONOutput(record, "")); err != nil {
+				break
+			}
 		}
-		noEscQuotes = noEscQuotes - 1
-		noQuotes = noQuotes - 1
+	case opts.first && len(records) == 1:
+		_, err = fmt.Fprintf(opts.out, "%s\n", createJSONOutput(records[0], opts.indent))
+	default:
+		_, err = fmt.Fprintf(opts.out, "%s\n", createJSONOutput(records, opts.indent))
 	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		exit(exitIOError)
+	}
+}
+
+func readTestStrings(filename string) ([]string, error) {
 
-	if noEscQuotes%2 != 0 || noQuotes%2 != 0 {
-		return "", fmt.Errorf("unterminated quoted part")
+	lines := []string{}
+
+	fd, err := os.Open(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, err
 	}
 
-	return str, nil
-}
+	scanner := bufio.NewScanner(fd)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	for scanner.Scan() {
+		line := scanner.Text()
 
-// count how many email are in a "from:" string
-//
-// Return number of email in the pattern name@web.com with and without <> ()
-func countNoEmails(input string) int {
-	// Regex matches content within < > that contains an @ symbol
-	re := regexp.MustCompile(`<([^>]+@[^>]+)>`)
-	matches := re.FindAllStringSubmatch(input, -1)
+		//line == "" handles both cases transparently because bufio.Scanner automatically strips \r\n(Windows) or \n(Linux/macOS)
+		if line == "" {
+			break
+		}
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			err = errLineTooLong
+		}
+		fd.Close()
+		fmt.Fprintln(os.Stderr, err)
+		return nil, err
+	}
 
-	return len(matches)
+	//close the opened file and check for error
+	err = fd.Close()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return nil, err
+	}
+
+	return lines, nil
 }
 
-// extract the display name and email address from a string
-// using 4 different representations of a display name and email
+// run test cases using different combinations of display name and email address
+// In -check mode only the errors are printed to stderr
 //
-// Returns a map of a (display name and an email)
-func parseDisplayNameAndEmail(str string) map[string]string {
-	retVal := make(map[string]string)
+// Return an error if the file with the test strings can not be read
+// or errCheckFailed if at least one test string fails in -check mode
+func doCustomFileTests(parser *emlparse.Parser, filename string, opts outputOptions) error {
+	var emails, err = readTestStrings(filename)
 
-	str = removeNestedComments(str)
-	str = strings.TrimSpace(str)
+	if err != nil {
+		return err
+	}
 
-	// 1st try: display name and <email>
-	bracketRe := regexp.MustCompile(`(?i)^"?([^"<]*)"?\s*<\s*([^@\s<>]+@[^@\s<>]+\.[^@\s<>]+)\s*>$`)
-	if m := bracketRe.FindStringSubmatch(str); m != nil {
-		retVal["display_name"] = m[1]
-		retVal["addr_spec"] = m[2]
+	failed := false
 
-		return retVal
-	}
+	// test each input string in the array
+	for _, fromStr := range emails {
+		infos, err := parser.ParseAddressList(fromStr)
+		opts.stats.add(err)
+		if opts.check {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", fromStr, err)
+				failed = true
+			}
+			continue
+		}
 
-	// 2nd try: display name and bare email(no angle brackets)
-	bareNameEmailRe := regexp.MustCompile(`(?i)^([^<"\s@][^<@"]*)\s+([a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,})$`)
-	if m := bareNameEmailRe.FindStringSubmatch(str); m != nil {
-		retVal["display_name"] = m[1]
-		retVal["addr_spec"] = m[2]
+		jsonOut := buildJSONOutput(infos, err, opts)
+		annotateHeader(jsonOut, nil, headerData{value: fromStr}, opts)
+		printOutput(jsonOut, opts)
+	}
 
-		return retVal
+	if failed {
+		return errCheckFailed
 	}
 
-	// 3rd try: just angle brackets email
-	bracketOnlyRe := regexp.MustCompile(`(?i)^<\s*([^@\s<>]+@[^@\s<>]+\.[^@\s<>]+)\s*>$`)
-	if m := bracketOnlyRe.FindStringSubmatch(str); m != nil {
-		retVal["display_name"] = ""
-		retVal["addr_spec"] = m[1]
+	return nil
+}
+
+// parse a "From:" string given on the command line
+// It is the single string equivalent of the test file, in -check mode only the error is printed to stderr
+//
+// Return an error if the string does not pass the validation
+func doFromString(parser *emlparse.Parser, fromStr string, opts outputOptions) error {
 
-		return retVal
+	infos, err := parser.ParseAddressList(fromStr)
+	if opts.check {
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+		return err
 	}
 
-	// 4th try: just plain email only, no angle brackets
-	emailRe := regexp.MustCompile(`(?i)^([a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,})$`)
-	if m := emailRe.FindStringSubmatch(str); m != nil {
-		retVal["display_name"] = ""
-		retVal["addr_spec"] = m[1]
+	jsonOut := buildJSONOutput(infos, err, opts)
+	annotateHeader(jsonOut, nil, headerData{value: fromStr}, opts)
+	printOutput(jsonOut, opts)
+
+	return err
+}
 
-		return retVal
+// print a formatted From: value for every name<TAB>email line in a file to w
+// A line without a tab contains only the email
+//
+// Return an error if the file can not be read
+func doFormatAddresses(filename string, w io.Writer) error {
+	lines, err := readTestStrings(filename)
+	if err != nil {
+		return err
 	}
-	return retVal
+
+	for _, line := range lines {
+		name, email, found := strings.Cut(line, "\t")
+		if !found {
+			name, email = "", line
+		}
+		fmt.Fprintln(w, emlparse.FormatAddress(name, email))
+	}
+
+	return nil
 }
 
-// build a json structure from a structure
+// build a json structure from a structure or a list of structures
+// indent is the indentation of every level, an empty indent puts the whole json on a single line
 //
 // Returns a json byte array
-func createJSONOutput(output jsonOutput) []byte {
+func createJSONOutput(output any, indent string) []byte {
 
-	jsonOutput, err := json.MarshalIndent(output, "", "  ")
+	var jsonOutput []byte
+	var err error
+	if indent == "" {
+		jsonOutput, err = json.Marshal(output)
+	} else {
+		jsonOutput, err = json.MarshalIndent(output, "", indent)
+	}
 	if err != nil {
-		fmt.Printf("Error generating JSON output: %v", err)
-		os.Exit(1)
+		fmt.Fprintf(os.Stderr, "Error generating JSON output: %v\n", err)
+		exit(exitIOError)
 	}
 	return jsonOutput
 }
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/linuxmk/eml-sender/emlparse"
+)
+
+func TestParseFileEncodings(t *testing.T) {
+
+	tests := []struct {
+		file    string
+		wantErr error
+	}{
+		{file: "utf8.eml"},
+		{file: "utf8-bom.eml"},
+		{file: "utf16le.eml"},
+		{file: "utf16le-bom.eml"},
+		{file: "utf16be.eml"},
+		{file: "utf16be-bom.eml"},
+		{file: "bom-only.eml", wantErr: emlparse.ErrEmptyInput},
+		{file: "utf16le-bom-only.eml", wantErr: emlparse.ErrEmptyInput},
+		{file: "empty.eml", wantErr: emlparse.ErrEmptyInput},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			addresses, _, err := parseFile(&emlparse.Parser{}, filepath.Join("testdata", tt.file), []string{"From"}, false)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) || exitCode(err) != exitHeaderMissing {
+					t.Fatalf("parseFile error = %v(exit status %d), want %v", err, exitCode(err), tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFile unexpected error: %v", err)
+			}
+			if len(addresses) != 1 || addresses[0].DisplayName != "Jürgen" || addresses[0].AddrSpec != "j@x.com" {
+				t.Errorf("parseFile = %+v, want Jürgen <j@x.com>", addresses)
+			}
+		})
+	}
+}
+
+func TestAllHeadersExitCode(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		email string
+		want  int
+	}{
+		{name: "valid headers", email: "From: a@x.com\r\nTo: b@x.com\r\n\r\n", want: exitOK},
+		{name: "empty To", email: "From: a@x.com\r\nTo:\r\n\r\n", want: exitOK},
+		{name: "empty To and Cc", email: "From: a@x.com\r\nTo:\r\nCc: \r\n\r\n", want: exitOK},
+		{name: "empty To and invalid Cc", email: "From: a@x.com\r\nTo:\r\nCc: bad\r\n\r\n", want: exitValidation},
+		{name: "no address header", email: "Subject: hi\r\n\r\n", want: exitHeaderMissing},
+		{name: "empty input", email: "", want: exitHeaderMissing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename := filepath.Join(t.TempDir(), "mail.eml")
+			if err := os.WriteFile(filename, []byte(tt.email), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := doAllHeaders(&emlparse.Parser{}, filename, outputOptions{out: io.Discard})
+			got := exitOK
+			if err != nil {
+				got = exitCode(err)
+			}
+			if got != tt.want {
+				t.Errorf("doAllHeaders exit status = %d(%v), want %d", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitAddrQuotedLocalPart(t *testing.T) {
+
+	addresses, err := emlparse.ParseAddressList(`"x@y"@z.com`)
+	if err != nil {
+		t.Fatalf("ParseAddressList unexpected error: %v", err)
+	}
+
+	records := buildJSONOutput(addresses, nil, outputOptions{splitAddr: true})
+	if len(records) != 1 || records[0].LocalPart != "x@y" || records[0].Domain != "z.com" {
+		t.Errorf("-split-addr = %+v, want local_part x@y and domain z.com", records)
+	}
+}
+
+func TestCloseOutput(t *testing.T) {
+
+	fd, err := os.Create(filepath.Join(t.TempDir(), "results.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := closeOutput(fd, exitOK); got != exitOK {
+		t.Errorf("closeOutput exit status = %d, want %d", got, exitOK)
+	}
+
+	//the second Close fails like a write error reported on close
+	if got := closeOutput(fd, exitOK); got != exitIOError {
+		t.Errorf("closeOutput of a closed file exit status = %d, want %d", got, exitIOError)
+	}
+	if got := closeOutput(fd, exitValidation); got != exitValidation {
+		t.Errorf("closeOutput of a closed file exit status = %d, want %d", got, exitValidation)
+	}
+	if got := closeOutput(nil, exitOK); got != exitOK {
+		t.Errorf("closeOutput without a file exit status = %d, want %d", got, exitOK)
+	}
+}
+
+func TestEmailFileStats(t *testing.T) {
+
+	tests := []struct {
+		file string
+		want batchStats
+	}{
+		{file: "utf8.eml", want: batchStats{Total: 1, Parsed: 1}},
+		{file: "empty.eml", want: batchStats{Total: 1, HeaderMissing: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			opts := outputOptions{out: io.Discard, stats: &batchStats{}}
+			doEmailFile(&emlparse.Parser{}, filepath.Join("testdata", tt.file), []string{"From"}, opts)
+			if *opts.stats != tt.want {
+				t.Errorf("doEmailFile -stats = %+v, want %+v", *opts.stats, tt.want)
+			}
+		})
+	}
+}
+
+func TestDateField(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{name: "RFC 1123Z date", value: "Mon, 02 Jan 2006 15:04:05 -0700", want: `{"raw":"Mon, 02 Jan 2006 15:04:05 -0700","time":"2006-01-02T15:04:05-07:00"}`},
+		{name: "malformed date", value: "yesterday at noon", want: "null"},
+		{name: "missing header", value: "", want: "null"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(dateField(tt.value)); got != tt.want {
+				t.Errorf("dateField(%q) = %s, want %s", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,520 @@
+// Package emlparse extracts the display name and the email address(addr-spec)
+// from the value of an address header like "From:", "To:" or "Cc:"
+package emlparse
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// DefaultMaxLength is the maximum length of a single mailbox used when Parser.MaxLength is 0
+// Longer mailboxes are rejected before they are matched against the patterns
+const DefaultMaxLength = 4096
+
+// Address structure contains the data extracted from a single mailbox
+// It contains the display name and the email address
+type Address struct {
+	DisplayName string
+	AddrSpec    string
+
+	// Group is the display name of the group(RFC 5322 group syntax) the address is a member of
+	Group string
+
+	// EmptyGroup is true for the address that stands for a group without members, e.g. "Undisclosed recipients:;",
+	// only Group is set, so the group name is kept in the list
+	EmptyGroup bool
+
+	// Comment is the text of the comments in "()", only set by a Parser with Comments enabled
+	Comment string
+
+	// CommentName is the text of the comment after the addr-spec, the old style display name,
+	// e.g. nickname in "Real Name" <a@x.com> (nickname) or John Doe in jdoe@x.com (John Doe)
+	// DisplayName is always the quoted or plain display name, a Parser with Comments enabled
+	// only uses CommentName as DisplayName when the mailbox has no display name
+	CommentName string
+
+	// AddrSpecASCII is the addr-spec with the domain in punycode(ACE) form, only set by a Parser with IDN enabled
+	AddrSpecASCII string
+
+	// ObsoleteRoute is true when the address had an obsolete source route(RFC 5322 obs-route),
+	// e.g. <@host1,@host2:user@example.com>, the route is not part of AddrSpec
+	ObsoleteRoute bool
+
+	// NullReturnPath is true for the null return path <> of a bounce message, only set by ParseReturnPath
+	NullReturnPath bool
+
+	// AngleBrackets is true when the addr-spec was written in angle brackets, e.g. <a@x.com>,
+	// the brackets are not part of AddrSpec
+	AngleBrackets bool
+
+	// Mailto is true when the address was a mailto: URI, e.g. mailto:a@x.com?subject=hi,
+	// the scheme and the query are not part of AddrSpec
+	Mailto bool
+
+	// Cleaned is true when a stray trailing ";" or "," was removed before parsing, e.g. a@b.com;
+	Cleaned bool
+
+	// HomographWarning describes a domain label that can be mistaken for another domain,
+	// e.g. with a Cyrillic "а" in paypal, only set by a Parser with Homograph enabled
+	HomographWarning string
+
+	// Warnings lists the non-fatal issues of a valid address, e.g. an obsolete source route or a comment
+	Warnings []string
+
+	// MatchRule is the name of the pattern that matched the mailbox:
+	// bracket, bare-name-email, bracket-only, plain-email, bracket-name or extra
+	MatchRule string
+}
+
+// Parser structure contains the options used while parsing the addresses
+// The zero value parses with the default rules
+type Parser struct {
+	// StrictTLD rejects addresses whose top level domain is not in the bundled list of emlparse/tlds.txt
+	StrictTLD bool
+
+	// IDN fills Address.AddrSpecASCII with the punycode form of internationalized domains
+	IDN bool
+
+	// Comments fills Address.Comment and uses a trailing comment as display name
+	// when the address has no display name, e.g. jdoe@x.com (John Doe)
+	Comments bool
+
+	// ExtraPattern is tried after the built-in patterns for nonstandard formats,
+	// it must have the named capture groups display_name(optional) and addr_spec
+	ExtraPattern *regexp.Regexp
+
+	// MaxLength is the maximum length of a single mailbox in bytes, 0 uses DefaultMaxLength
+	MaxLength int
+
+	// RFC5321 rejects addresses over the SMTP length limits: local-part 64, domain 255 and addr-spec 254 octets
+	RFC5321 bool
+
+	// WarningsAsErrors rejects an address with any of the KnownWarnings with ErrWarning
+	WarningsAsErrors bool
+
+	// NormalizePunct replaces the smart punctuation of the display name with ASCII(NormalizePunctuation),
+	// the addr-spec is never changed
+	NormalizePunct bool
+
+	// NFC applies the Unicode normalization form C to the display name, so a precomposed "é"
+	// and "e" with a combining accent compare equal
+	NFC bool
+
+	// Homograph fills Address.HomographWarning for domains with mixed scripts or confusable characters
+	Homograph bool
+
+	// NoBareName disables the bare-name-email pattern(John Doe jdoe@x.com), which is not RFC 5322 syntax,
+	// so only a quoted or plain display name with the address in angle brackets is accepted
+	NoBareName bool
+}
+
+// ParseAddress extracts the display name and email address from a string
+// containing a single mailbox, e.g. "Peter Pan" <peter@pan.com>
+//
+// Returns an error if the string does not pass the validation or contains more than one mailbox
+func ParseAddress(input string) (Address, error) {
+	return (&Parser{}).ParseAddress(input)
+}
+
+// ParseAddressList extracts the display name and email address of every mailbox in a string
+//
+// Returns an error if one of the mailboxes does not pass the validation
+// or a list of addresses, in the order they appear
+func ParseAddressList(input string) ([]Address, error) {
+	return (&Parser{}).ParseAddressList(input)
+}
+
+// ParseAddress extracts the display name and email address from a string
+// containing a single mailbox using the options of the parser
+//
+// Returns an error if the string does not pass the validation or contains more than one mailbox
+func (p *Parser) ParseAddress(input string) (Address, error) {
+
+	addresses, err := p.ParseAddressList(input)
+	if err != nil {
+		return Address{}, err
+	}
+
+	//an empty group has no mailbox, only the address that keeps its name
+	if len(addresses) == 0 || addresses[0].EmptyGroup {
+		return Address{}, ErrNoAddrSpec
+	}
+	if len(addresses) > 1 {
+		return Address{}, fmt.Errorf("%w: found %d mailboxes", ErrMultipleAddrs, len(addresses))
+	}
+
+	return addresses[0], nil
+}
+
+// ParseAddressList extracts the display name and email address of every mailbox in a string
+// using the options of the parser
+// The members of a group are added with the group name, an empty group("Undisclosed recipients:;")
+// adds one address with EmptyGroup set and only the group name
+//
+// Returns an error if one of the mailboxes does not pass the validation
+// or a list of addresses, in the order they appear
+func (p *Parser) ParseAddressList(input string) ([]Address, error) {
+
+	if err := checkControlChars(input); err != nil {
+		return nil, err
+	}
+	input = strings.TrimSpace(unfoldHeader(input))
+
+	//a trailing "," is dropped by splitAddressList, it is only noted on the last address
+	trailingComma := strings.HasSuffix(input, ",")
+
+	retVal := []Address{}
+	cursor := 0
+
+	for _, item := range splitAddressList(input) {
+		_, _, isGroup := splitGroup(item)
+		item, cleaned := trimStrayPunctuation(item, isGroup)
+		offset := itemOffset(input, item, &cursor)
+
+		//a group "name: member, member;" adds all of its members
+		if isGroup {
+			group, err := p.ParseGroup(item)
+			if err != nil {
+				return nil, withOffset(err, offset)
+			}
+			for i := range group.Members {
+				group.Members[i].Cleaned = cleaned
+			}
+			retVal = append(retVal, group.Members...)
+			if len(group.Members) == 0 {
+				retVal = append(retVal, Address{Group: group.Name, EmptyGroup: true, Cleaned: cleaned})
+			}
+			continue
+		}
+
+		address, err := p.parseMailbox(item)
+		if err != nil {
+			return nil, withOffset(err, offset)
+		}
+		address.Cleaned = cleaned
+		retVal = append(retVal, address)
+	}
+
+	if trailingComma && len(retVal) > 0 {
+		retVal[len(retVal)-1].Cleaned = true
+	}
+
+	return retVal, nil
+}
+
+// find the byte offset of an item of the address list in the header value
+// The items are searched from the cursor on, which is moved after the item
+//
+// Returns the offset of the item or the cursor if it is not found
+func itemOffset(input string, item string, cursor *int) int {
+	offset := *cursor
+	if i := strings.Index(input[*cursor:], item); i >= 0 {
+		offset += i
+		*cursor = offset + len(item)
+	}
+	return offset
+}
+
+// remove the stray ";" and "," at the end of a mailbox, e.g. a@b.com; copied with the separator of another list
+// The ";" that closes a group("name: a@b.com;") is kept, only the ones after it are removed
+//
+// Returns the mailbox without the stray punctuation and true if something was removed
+func trimStrayPunctuation(item string, isGroup bool) (string, bool) {
+
+	item = strings.TrimSpace(item)
+	closing := ""
+	if isGroup && strings.HasSuffix(item, ";") {
+		item, closing = item[:len(item)-1], ";"
+	}
+
+	trimmed := strings.TrimRight(item, ";, \t")
+	cleaned := trimmed != strings.TrimRight(item, " \t")
+
+	return trimmed + closing, cleaned
+}
+
+// quotedSpecials replaces the angle brackets and the "@" of a quoted display name with control characters,
+// which checkControlChars guarantees are not in the input, and unquotedSpecials restores them
+var (
+	quotedSpecials   = strings.NewReplacer("<", "\x01", ">", "\x02", "@", "\x03")
+	unquotedSpecials = strings.NewReplacer("\x01", "<", "\x02", ">", "\x03", "@")
+)
+
+// obsoleteRouteRe matches the obsolete source route after the "<", e.g. <@host1,@host2:
+var obsoleteRouteRe = regexp.MustCompile(`<\s*(?:,\s*)*@[^<>:"]*:\s*`)
+
+// remove the obsolete source route(RFC 5322 obs-route) from a mailbox, <@host1,@host2:user@example.com>
+//
+// Returns the mailbox without the route and false if there is no route
+func removeObsoleteRoute(mailbox string) (string, bool) {
+	if !obsoleteRouteRe.MatchString(mailbox) {
+		return mailbox, false
+	}
+	return obsoleteRouteRe.ReplaceAllString(mailbox, "<"), true
+}
+
+// extract the display name and email address from a single mailbox string
+// using the options of the parser
+//
+// Returns an error if the mailbox does not pass the validation
+func (p *Parser) parseMailbox(mailbox string) (Address, error) {
+
+	//a pathological input is rejected before it is fed to the regex engine
+	maxLength := p.MaxLength
+	if maxLength <= 0 {
+		maxLength = DefaultMaxLength
+	}
+	if len(mailbox) > maxLength {
+		return Address{}, ErrTooLong
+	}
+
+	mailbox, mailto, err := stripMailto(mailbox)
+	if err != nil {
+		return Address{}, err
+	}
+	comment, trailingComment := collectComments(mailbox)
+
+	mailbox, err = checkForErrors(mailbox)
+	if err != nil {
+		return Address{}, err
+	}
+
+	//a quoted local-part("john doe"@x.com) is kept as a single token
+	mailbox, quotedLocal, hasQuotedLocal := extractQuotedLocalPart(mailbox)
+
+	//angle brackets and "@" in a quoted display name("<<VIP>>" <v@x.com>, "sales@company" <s@x.com>) are not part of the address
+	mailbox = quotedStringRe.ReplaceAllStringFunc(mailbox, quotedSpecials.Replace)
+
+	//the obsolete source route is discarded, only the final addr-spec is delivered to
+	mailbox, hasRoute := removeObsoleteRoute(mailbox)
+
+	//First, clean the input by trimming whitespace and special chars
+	//the curly quotes are replaced as whole UTF-8 sequences, so the bytes of other multibyte characters are not touched
+	mailbox = strings.ReplaceAll(mailbox, "“", `"`)
+	mailbox = strings.ReplaceAll(mailbox, "”", `"`)
+	mailbox = strings.ReplaceAll(mailbox, "\"", ``)
+
+	//workhorse of the application
+	//parses the input string extracted from the email
+	address, ok := p.parseDisplayNameAndEmail(mailbox)
+	if !ok {
+		return Address{}, ErrNoMatch
+	}
+
+	//a decoded encoded-word can contain a CR or LF
+	if err := checkHeaderInjection(address.DisplayName, address.AddrSpec); err != nil {
+		return Address{}, err
+	}
+
+	//the whitespace around a removed comment, Alice <a@x.com> (sent via phone), is not part of the display name
+	address.DisplayName = strings.TrimSpace(unquotedSpecials.Replace(address.DisplayName))
+	if p.NormalizePunct {
+		address.DisplayName = NormalizePunctuation(address.DisplayName)
+	}
+	if p.NFC {
+		address.DisplayName = norm.NFC.String(address.DisplayName)
+	}
+	address.ObsoleteRoute = hasRoute
+	address.Mailto = mailto
+	address.CommentName = trailingComment
+	address.Warnings = p.collectWarnings(address, comment, hasQuotedLocal)
+
+	if hasQuotedLocal && address.AddrSpec != "" {
+		address.AddrSpec = quotedLocal + address.AddrSpec[strings.Index(address.AddrSpec, "@"):]
+	}
+
+	if p.Comments {
+		address.Comment = comment
+		if address.DisplayName == "" {
+			address.DisplayName = trailingComment
+		}
+	}
+
+	if p.RFC5321 {
+		if err := checkLengths(address.AddrSpec); err != nil {
+			return Address{}, err
+		}
+	}
+
+	if p.StrictTLD && address.AddrSpec != "" && !isDomainLiteral(address.AddrSpec) {
+		if err := checkTLD(address.AddrSpec); err != nil {
+			return Address{}, err
+		}
+	}
+
+	if p.Homograph && address.AddrSpec != "" {
+		address.HomographWarning = checkHomograph(address.AddrSpec)
+	}
+
+	if p.IDN && address.AddrSpec != "" && !isDomainLiteral(address.AddrSpec) {
+		address.AddrSpecASCII, err = toASCIIAddrSpec(address.AddrSpec)
+		if err != nil {
+			return Address{}, err
+		}
+	}
+
+	if err := p.warningsError(address); err != nil {
+		return Address{}, err
+	}
+
+	return address, nil
+}
+
+// split a "From:" string into the separate mailboxes of the list
+// Commas inside quoted strings, comments, angle brackets and groups("name: a, b;") are not separators
+//
+// Returns the list of mailbox(or group) strings, empty list elements are skipped
+func splitAddressList(s string) []string {
+
+	var parts []string
+	var sb strings.Builder
+	inQuote := false
+	inGroup := false
+	depth := 0
+	angles := 0
+	literal := false
+
+	for i := 0; i < len(s); i++ {
+		char := s[i]
+
+		switch {
+		case char == '\\' && i+1 < len(s):
+			//escaped character, copy it together with the backslash
+			sb.WriteByte(char)
+			i++
+			char = s[i]
+		case char == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case char == '(':
+			depth++
+		case char == ')' && depth > 0:
+			depth--
+		case depth > 0:
+		case char == '[':
+			literal = true
+		case char == ']':
+			literal = false
+		case literal:
+		case char == '<':
+			angles++
+		case char == '>' && angles > 0:
+			angles--
+		case char == ':' && angles == 0 && isMailtoColon(s, i):
+			//a colon in the address of a mailto: URI, e.g. mailto:a%0D%0ABcc:x@y.com, is not the colon of a group
+			end := mailtoEnd(s, i)
+			sb.WriteString(s[i:end])
+			i = end - 1
+			continue
+		case char == ':' && angles == 0:
+			inGroup = true
+		case char == ';' && inGroup:
+			inGroup = false
+		case char == ',' && angles == 0 && !inGroup:
+			if part := strings.TrimSpace(sb.String()); part != "" {
+				parts = append(parts, part)
+			}
+			sb.Reset()
+			continue
+		}
+		sb.WriteByte(char)
+	}
+
+	if part := strings.TrimSpace(sb.String()); part != "" {
+		parts = append(parts, part)
+	}
+
+	return parts
+}
+
+// the patterns of parseDisplayNameAndEmail, compiled once and not on every mailbox
+// The domain can also be a domain-literal: user@[192.168.1.1] or user@[IPv6:2001:db8::1]
+var (
+	bracketRe       = regexp.MustCompile(`(?i)^"?([^"<]+)"?\s*<\s*([^@\s<>]+@(?:[^@\s<>\[\]]+\.[^@\s<>]+|\[[^\[\]\s<>]+\]))\s*>$`)
+	bareNameEmailRe = regexp.MustCompile(`(?i)^([^<"\s@][^<@"]*)\s+([\p{L}\p{N}._%+\-]+@(?:[\p{L}\p{N}.\-]+\.(?:\p{L}{2,}|xn--[a-z0-9\-]+)|\[[^\[\]\s]+\]))$`)
+	bracketOnlyRe   = regexp.MustCompile(`(?i)^<\s*([^@\s<>]+@(?:[^@\s<>\[\]]+\.[^@\s<>]+|\[[^\[\]\s<>]+\]))\s*>$`)
+	emailRe         = regexp.MustCompile(`(?i)^([\p{L}\p{N}._%+\-]+@(?:[\p{L}\p{N}.\-]+\.(?:\p{L}{2,}|xn--[a-z0-9\-]+)|\[[^\[\]\s]+\]))$`)
+	bracketNameRe   = regexp.MustCompile(`(?i)^<\s*([^@\s<>]+@(?:[^@\s<>\[\]]+\.[^@\s<>]+|\[[^\[\]\s<>]+\]))\s*>\s*([^<>@]+)$`)
+)
+
+// extract the display name and email address from a string
+// using 5 different representations of a display name and email
+// and then the extra pattern of the parser, if it is not nil
+// The bare-name-email pattern is skipped with NoBareName
+//
+// Returns an address (display name and an email) and false if none of the patterns matched
+func (p *Parser) parseDisplayNameAndEmail(str string) (Address, bool) {
+	retVal := Address{}
+
+	str = removeNestedComments(str)
+	str = strings.TrimSpace(str)
+
+	// 1st try: display name and <email>, a mailbox without a display name is left to bracket-only
+	if m := bracketRe.FindStringSubmatch(str); m != nil {
+		retVal.DisplayName = decodeEncodedWord(m[1])
+		retVal.AddrSpec = m[2]
+		retVal.MatchRule = "bracket"
+		retVal.AngleBrackets = true
+
+		return retVal, true
+	}
+
+	// 2nd try: display name and bare email(no angle brackets)
+	if !p.NoBareName {
+		if m := bareNameEmailRe.FindStringSubmatch(str); m != nil {
+			retVal.DisplayName = decodeEncodedWord(m[1])
+			retVal.AddrSpec = m[2]
+			retVal.MatchRule = "bare-name-email"
+
+			return retVal, true
+		}
+	}
+
+	// 3rd try: just angle brackets email
+	if m := bracketOnlyRe.FindStringSubmatch(str); m != nil {
+		retVal.DisplayName = ""
+		retVal.AddrSpec = m[1]
+		retVal.MatchRule = "bracket-only"
+		retVal.AngleBrackets = true
+
+		return retVal, true
+	}
+
+	// 4th try: just plain email only, no angle brackets
+	if m := emailRe.FindStringSubmatch(str); m != nil {
+		retVal.DisplayName = ""
+		retVal.AddrSpec = m[1]
+		retVal.MatchRule = "plain-email"
+
+		return retVal, true
+	}
+
+	// 5th try: angle brackets email and the display name after it, <j@x.com> John Doe
+	if m := bracketNameRe.FindStringSubmatch(str); m != nil {
+		retVal.DisplayName = decodeEncodedWord(strings.Trim(m[2], " \t\"'"))
+		retVal.AddrSpec = m[1]
+		retVal.MatchRule = "bracket-name"
+		retVal.AngleBrackets = true
+
+		return retVal, true
+	}
+
+	// 6th try: the user defined pattern with the named groups display_name and addr_spec
+	if extra := p.ExtraPattern; extra != nil {
+		if m := extra.FindStringSubmatch(str); m != nil {
+			if i := extra.SubexpIndex("display_name"); i >= 0 {
+				retVal.DisplayName = decodeEncodedWord(strings.TrimSpace(m[i]))
+			}
+			if i := extra.SubexpIndex("addr_spec"); i >= 0 {
+				retVal.AddrSpec = m[i]
+			}
+			retVal.MatchRule = "extra"
+
+			return retVal, true
+		}
+	}
+	return retVal, false
+}
@@ -0,0 +1,396 @@
+package emlparse
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/idna"
+)
+
+// wantAddress contains the fields of an Address that the table tests compare
+type wantAddress struct {
+	name     string
+	addrSpec string
+	rule     string
+	comment  string
+	warnings []string
+}
+
+// convert the parsed addresses to the compared fields
+//
+// Returns the compared fields of every address
+func gotAddresses(addresses []Address) []wantAddress {
+	got := []wantAddress{}
+	for _, a := range addresses {
+		got = append(got, wantAddress{name: a.DisplayName, addrSpec: a.AddrSpec, rule: a.MatchRule, comment: a.CommentName, warnings: a.Warnings})
+	}
+	return got
+}
+
+func TestParseAddressList(t *testing.T) {
+
+	noName := []string{WarnNoDisplayName}
+
+	tests := []struct {
+		name    string
+		input   string
+		want    []wantAddress
+		wantErr error
+	}{
+		{
+			name:  "quoted display name and angle brackets",
+			input: `"Peter Walters" <peter@company.com>`,
+			want:  []wantAddress{{name: "Peter Walters", addrSpec: "peter@company.com", rule: "bracket"}},
+		},
+		{
+			name:  "plain display name and bare address",
+			input: `John Doe jdoe@x.com`,
+			want:  []wantAddress{{name: "John Doe", addrSpec: "jdoe@x.com", rule: "bare-name-email"}},
+		},
+		{
+			name:  "angle brackets only",
+			input: `<peter@company.com>`,
+			want:  []wantAddress{{addrSpec: "peter@company.com", rule: "bracket-only", warnings: noName}},
+		},
+		{
+			name:  "plain address",
+			input: `peter@company.com`,
+			want:  []wantAddress{{addrSpec: "peter@company.com", rule: "plain-email", warnings: noName}},
+		},
+		{
+			name:  "display name after the address",
+			input: `<j@x.com> John Doe`,
+			want:  []wantAddress{{name: "John Doe", addrSpec: "j@x.com", rule: "bracket-name"}},
+		},
+		{
+			name:  "quoted display name after the address",
+			input: `<j@x.com>   "John Doe"  `,
+			want:  []wantAddress{{name: "John Doe", addrSpec: "j@x.com", rule: "bracket-name"}},
+		},
+		{
+			//the comment is removed before matching, the name is only kept as comment_name
+			name:  "comment after the address",
+			input: `<j@x.com> (John Doe)`,
+			want:  []wantAddress{{addrSpec: "j@x.com", rule: "bracket-only", comment: "John Doe", warnings: []string{WarnComment}}},
+		},
+		{
+			name:  "empty quoted display name",
+			input: `"" <a@x.com>`,
+			want:  []wantAddress{{addrSpec: "a@x.com", rule: "bracket-only", warnings: noName}},
+		},
+		{
+			name:  "quoted display name with a comma",
+			input: `"Doe, John" <j@x.com>`,
+			want:  []wantAddress{{name: "Doe, John", addrSpec: "j@x.com", rule: "bracket"}},
+		},
+		{
+			name:  "quoted display name with an @",
+			input: `"me@home" <sales@x.com>`,
+			want:  []wantAddress{{name: "me@home", addrSpec: "sales@x.com", rule: "bracket"}},
+		},
+		{
+			name:  "comment between name and address",
+			input: `"Peter" (Sally's friend) <peter@pan.com>`,
+			want:  []wantAddress{{name: "Peter", addrSpec: "peter@pan.com", rule: "bracket", warnings: []string{WarnComment}}},
+		},
+		{
+			name:  "nested comments",
+			input: `Peter (a (b) c) <peter@pan.com>`,
+			want:  []wantAddress{{name: "Peter", addrSpec: "peter@pan.com", rule: "bracket", warnings: []string{WarnComment}}},
+		},
+		{
+			name:  "comments around a plain address",
+			input: `(John) j@x.com (Smith)`,
+			want:  []wantAddress{{addrSpec: "j@x.com", rule: "plain-email", comment: "Smith", warnings: []string{WarnComment}}},
+		},
+		{
+			name:  "address list",
+			input: `a@x.com, B <b@y.com>`,
+			want: []wantAddress{
+				{addrSpec: "a@x.com", rule: "plain-email", warnings: noName},
+				{name: "B", addrSpec: "b@y.com", rule: "bracket"},
+			},
+		},
+		{
+			name:  "address list with quoted commas",
+			input: `"Doe, John" <j@x.com>, "Roe, Jane" <r@x.com>`,
+			want: []wantAddress{
+				{name: "Doe, John", addrSpec: "j@x.com", rule: "bracket"},
+				{name: "Roe, Jane", addrSpec: "r@x.com", rule: "bracket"},
+			},
+		},
+		{
+			name:  "quoted local-part with a space",
+			input: `"john doe"@example.com`,
+			want:  []wantAddress{{addrSpec: "john doe@example.com", rule: "plain-email", warnings: []string{WarnQuotedLocal, WarnNoDisplayName}}},
+		},
+		{
+			name:  "quoted local-part with consecutive dots",
+			input: `"john..doe"@example.com`,
+			want:  []wantAddress{{addrSpec: "john..doe@example.com", rule: "plain-email", warnings: []string{WarnQuotedLocal, WarnNoDisplayName}}},
+		},
+		{
+			name:  "quoted local-part with an escaped quote",
+			input: `"john\"doe"@example.com`,
+			want:  []wantAddress{{addrSpec: `john"doe@example.com`, rule: "plain-email", warnings: []string{WarnQuotedLocal, WarnNoDisplayName}}},
+		},
+		{
+			name:  "quoted local-part in angle brackets",
+			input: `Name <"john doe"@example.com>`,
+			want:  []wantAddress{{name: "Name", addrSpec: "john doe@example.com", rule: "bracket", warnings: []string{WarnQuotedLocal}}},
+		},
+		{
+			name:  "quoted local-part with a dot and a quoted display name",
+			input: `"Name" <"john.doe"@example.com>`,
+			want:  []wantAddress{{name: "Name", addrSpec: "john.doe@example.com", rule: "bracket", warnings: []string{WarnQuotedLocal}}},
+		},
+		{
+			name:  "quoted local-part with an @",
+			input: `"x@y"@z.com`,
+			want:  []wantAddress{{addrSpec: "x@y@z.com", rule: "plain-email", warnings: []string{WarnQuotedLocal, WarnNoDisplayName}}},
+		},
+		{
+			name:  "quoted local-part with an @ in angle brackets",
+			input: `Name <"x@y"@z.com>`,
+			want:  []wantAddress{{name: "Name", addrSpec: "x@y@z.com", rule: "bracket", warnings: []string{WarnQuotedLocal}}},
+		},
+		{
+			name:  "angle brackets in a quoted display name",
+			input: `"<<VIP>>" <v@x.com>`,
+			want:  []wantAddress{{name: "<<VIP>>", addrSpec: "v@x.com", rule: "bracket"}},
+		},
+		{
+			name:  "closing angle brackets in a quoted display name",
+			input: `"a >> b" <v@x.com>`,
+			want:  []wantAddress{{name: "a >> b", addrSpec: "v@x.com", rule: "bracket"}},
+		},
+		{
+			name:  "angle brackets in a comment",
+			input: `(see <<this>>) Peter <p@x.com>`,
+			want:  []wantAddress{{name: "Peter", addrSpec: "p@x.com", rule: "bracket", warnings: []string{WarnComment}}},
+		},
+		{
+			name:  "unquoted CJK display name",
+			input: `山田太郎 <yamada@example.jp>`,
+			want:  []wantAddress{{name: "山田太郎", addrSpec: "yamada@example.jp", rule: "bracket"}},
+		},
+		{
+			name:  "unquoted Cyrillic display name",
+			input: `Иван Петров <ivan@example.ru>`,
+			want:  []wantAddress{{name: "Иван Петров", addrSpec: "ivan@example.ru", rule: "bracket"}},
+		},
+		{
+			name:  "unquoted emoji display name",
+			input: `🎉 Party <party@example.com>`,
+			want:  []wantAddress{{name: "🎉 Party", addrSpec: "party@example.com", rule: "bracket"}},
+		},
+		{
+			name:  "CJK display name in curly quotes",
+			input: `“山田 太郎” <yamada@example.jp>`,
+			want:  []wantAddress{{name: "山田 太郎", addrSpec: "yamada@example.jp", rule: "bracket"}},
+		},
+		{
+			name:  "encoded-word CJK display name",
+			input: `=?UTF-8?B?5bGx55Sw5aSq6YOO?= <yamada@example.jp>`,
+			want:  []wantAddress{{name: "山田太郎", addrSpec: "yamada@example.jp", rule: "bracket"}},
+		},
+		{
+			name:  "encoded-word Cyrillic display name",
+			input: `=?UTF-8?B?0JjQstCw0L0g0J/QtdGC0YDQvtCy?= <ivan@example.ru>`,
+			want:  []wantAddress{{name: "Иван Петров", addrSpec: "ivan@example.ru", rule: "bracket"}},
+		},
+		{
+			name:  "encoded-word emoji display name",
+			input: `=?UTF-8?Q?=F0=9F=8E=89_Party?= <party@example.com>`,
+			want:  []wantAddress{{name: "🎉 Party", addrSpec: "party@example.com", rule: "bracket"}},
+		},
+		{
+			name:  "mailto with an escaped @ and a query",
+			input: `mailto:a%40x.com?subject=hi`,
+			want:  []wantAddress{{addrSpec: "a@x.com", rule: "plain-email", warnings: noName}},
+		},
+		{name: "missing domain", input: `Peter <peter>`, wantErr: ErrMissingDomain},
+		{name: "no addr-spec", input: `peter company.com`, wantErr: ErrNoAddrSpec},
+		{name: "local-part ending in a dot", input: `peter.@x.com`, wantErr: ErrLocalpartDot},
+		{name: "local-part starting with a dot", input: `.peter@x.com`, wantErr: ErrLocalpartDot},
+		{name: "nested angle brackets", input: `"Bill" <<b@x.com>>`, wantErr: ErrNestedBrackets},
+		{name: "unterminated quote", input: `"Bill <b@x.com>`, wantErr: ErrUnterminatedQuote},
+		{name: "unbalanced angle brackets", input: `Alice <a@x.com`, wantErr: ErrUnbalancedAngle},
+		{name: "unbalanced comment", input: `Alice (x <a@x.com>`, wantErr: ErrUnbalancedComment},
+		{name: "two addresses without a comma", input: `a@x.com b@y.com`, wantErr: ErrNoMatch},
+		{name: "mailto with an escaped CRLF", input: `mailto:a%0D%0ABcc:%20x@y.com`, wantErr: ErrHeaderInjection},
+		{name: "mailto with an escaped CRLF in a list", input: `b@c.com, mailto:a%0D%0ABcc:%20x@y.com`, wantErr: ErrHeaderInjection},
+		{name: "mailto without an address", input: `mailto:`, wantErr: ErrNoAddrSpec},
+		{name: "mailto without an address in a list", input: `a@x.com, mailto:`, wantErr: ErrNoAddrSpec},
+		{name: "mailto with only a query", input: `mailto:?subject=hi`, wantErr: ErrNoAddrSpec},
+		{name: "empty group without a name", input: `:;`, wantErr: ErrEmptyGroupName},
+		{name: "group without a name", input: `"" : a@x.com;`, wantErr: ErrEmptyGroupName},
+		{name: "control character", input: "a@x.com\r\nBcc: c@d.com", wantErr: ErrControlChar},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addresses, err := ParseAddressList(tt.input)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("ParseAddressList(%q) error = %v, want %v", tt.input, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseAddressList(%q) unexpected error: %v", tt.input, err)
+			}
+
+			got := gotAddresses(addresses)
+			if !slices.EqualFunc(got, tt.want, equalAddress) {
+				t.Errorf("ParseAddressList(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAddressListNestedBracketsPosition(t *testing.T) {
+
+	tests := []struct {
+		input    string
+		position int
+	}{
+		{input: `<<a@b.com>>`, position: 0},
+		{input: `"VIP" <<v@x.com>>`, position: 6},
+		{input: `Peter <<p@x.com>`, position: 6},
+		{input: `"<<VIP>>" <v@x.com>, X <<y@z.com>>`, position: 23},
+	}
+
+	for _, tt := range tests {
+		_, err := ParseAddressList(tt.input)
+
+		var perr *PositionError
+		if !errors.Is(err, ErrNestedBrackets) || !errors.As(err, &perr) || perr.Position != tt.position {
+			t.Errorf("ParseAddressList(%q) error = %#v, want %v at position %d", tt.input, err, ErrNestedBrackets, tt.position)
+		}
+	}
+}
+
+func TestParseAddressListEmptyGroup(t *testing.T) {
+
+	addresses, err := ParseAddressList(`a@x.com, Undisclosed recipients:;`)
+	if err != nil {
+		t.Fatalf("ParseAddressList unexpected error: %v", err)
+	}
+
+	want := []Address{{AddrSpec: "a@x.com"}, {Group: "Undisclosed recipients", EmptyGroup: true}}
+	if len(addresses) != len(want) {
+		t.Fatalf("ParseAddressList = %+v, want %d addresses", addresses, len(want))
+	}
+	for i, a := range addresses {
+		if a.AddrSpec != want[i].AddrSpec || a.Group != want[i].Group || a.EmptyGroup != want[i].EmptyGroup {
+			t.Errorf("address %d = %+v, want %+v", i, a, want[i])
+		}
+	}
+
+	//a single empty group has no mailbox
+	if _, err := ParseAddress(`Undisclosed recipients:;`); !errors.Is(err, ErrNoAddrSpec) {
+		t.Errorf("ParseAddress error = %v, want %v", err, ErrNoAddrSpec)
+	}
+
+	//RFC 5322 requires a display-name before the colon
+	if _, err := ParseGroup(`:;`); !errors.Is(err, ErrEmptyGroupName) {
+		t.Errorf("ParseGroup error = %v, want %v", err, ErrEmptyGroupName)
+	}
+}
+
+func TestParseAddressTrailingCommentName(t *testing.T) {
+
+	//with Comments the trailing comment is the display name of a mailbox without one
+	address, err := (&Parser{Comments: true}).ParseAddress(`<j@x.com> (John Doe)`)
+	if err != nil {
+		t.Fatalf("ParseAddress unexpected error: %v", err)
+	}
+	if address.DisplayName != "John Doe" || address.CommentName != "John Doe" || address.AddrSpec != "j@x.com" {
+		t.Errorf("ParseAddress = %q, %q, %q, want John Doe, John Doe, j@x.com", address.DisplayName, address.CommentName, address.AddrSpec)
+	}
+}
+
+func TestParseAddressIDNRoundTrip(t *testing.T) {
+
+	tests := []struct {
+		name      string
+		input     string
+		wantASCII string
+		wantBack  string
+	}{
+		{name: "ASCII domain", input: "a@x.com", wantASCII: "a@x.com", wantBack: "x.com"},
+		{name: "upper case ASCII domain", input: "a@X.COM", wantASCII: "a@x.com", wantBack: "x.com"},
+		{name: "Unicode domain", input: "用户@例え.jp", wantASCII: "用户@xn--r8jz45g.jp", wantBack: "例え.jp"},
+		{name: "mixed-script domain", input: "a@pаypal.com", wantASCII: "a@xn--pypal-4ve.com", wantBack: "pаypal.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, err := (&Parser{IDN: true}).ParseAddress(tt.input)
+			if err != nil {
+				t.Fatalf("ParseAddress(%q) unexpected error: %v", tt.input, err)
+			}
+			if address.AddrSpec != tt.input || address.AddrSpecASCII != tt.wantASCII {
+				t.Errorf("ParseAddress(%q) = %q, %q, want %q, %q", tt.input, address.AddrSpec, address.AddrSpecASCII, tt.input, tt.wantASCII)
+			}
+
+			//the punycode form decodes back to the original domain
+			domain := address.AddrSpecASCII[strings.LastIndex(address.AddrSpecASCII, "@")+1:]
+			back, err := idna.Lookup.ToUnicode(domain)
+			if err != nil || back != tt.wantBack {
+				t.Errorf("ToUnicode(%q) = %q, %v, want %q", domain, back, err, tt.wantBack)
+			}
+		})
+	}
+}
+
+func TestParseAddressMultiple(t *testing.T) {
+	_, err := ParseAddress(`a@x.com, b@y.com`)
+	if !errors.Is(err, ErrMultipleAddrs) {
+		t.Errorf("ParseAddress error = %v, want %v", err, ErrMultipleAddrs)
+	}
+}
+
+func TestParseDisplayNameAndEmail(t *testing.T) {
+
+	tests := []struct {
+		input    string
+		name     string
+		addrSpec string
+		rule     string
+		ok       bool
+	}{
+		{input: `"Peter Walters" <peter@company.com>`, name: "Peter Walters", addrSpec: "peter@company.com", rule: "bracket", ok: true},
+		{input: `John Doe jdoe@x.com`, name: "John Doe", addrSpec: "jdoe@x.com", rule: "bare-name-email", ok: true},
+		{input: `<p@c.com>`, addrSpec: "p@c.com", rule: "bracket-only", ok: true},
+		{input: `p@c.com`, addrSpec: "p@c.com", rule: "plain-email", ok: true},
+		{input: `<j@x.com> John Doe`, name: "John Doe", addrSpec: "j@x.com", rule: "bracket-name", ok: true},
+		{input: `user@[192.168.1.1]`, addrSpec: "user@[192.168.1.1]", rule: "plain-email", ok: true},
+		{input: `nothing here`},
+	}
+
+	for _, tt := range tests {
+		got, ok := (&Parser{}).parseDisplayNameAndEmail(tt.input)
+		if ok != tt.ok || got.DisplayName != tt.name || got.AddrSpec != tt.addrSpec || got.MatchRule != tt.rule {
+			t.Errorf("parseDisplayNameAndEmail(%q) = %q, %q, %q, %v, want %q, %q, %q, %v",
+				tt.input, got.DisplayName, got.AddrSpec, got.MatchRule, ok, tt.name, tt.addrSpec, tt.rule, tt.ok)
+		}
+	}
+
+	//with NoBareName the display name and bare address pattern is skipped
+	if _, ok := (&Parser{NoBareName: true}).parseDisplayNameAndEmail(`John Doe jdoe@x.com`); ok {
+		t.Errorf("parseDisplayNameAndEmail with NoBareName matched a bare name and address")
+	}
+}
+
+// compare the fields of two addresses, a nil and an empty warnings list are the same
+//
+// Returns true if the fields are equal
+func equalAddress(a, b wantAddress) bool {
+	return a.name == b.name && a.addrSpec == b.addrSpec && a.rule == b.rule && a.comment == b.comment &&
+		slices.Equal(a.warnings, b.warnings)
+}
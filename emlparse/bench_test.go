@@ -0,0 +1,98 @@
+package emlparse
+
+import (
+	"bufio"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// benchInputs are header values in the forms of a typical batch run, every pattern of parseDisplayNameAndEmail is used
+var benchInputs = []string{
+	`"Peter Walters" <peter@company.com>`,
+	`John Doe jdoe@x.com`,
+	`<peter@company.com>`,
+	`peter@company.com`,
+	`<j@x.com> John Doe`,
+	`"Vogel, Martin" <martin.vogel@sig.com>, a@x.com, B <b@y.com>`,
+	`"Peter" (Sally's friend) <peter@pan.com>`,
+	`=?UTF-8?B?SsO8cmdlbiBNw7xsbGVy?= <j@x.com>`,
+}
+
+// BenchmarkParseAddressList parses the inputs like the lines of a test file,
+// the patterns are compiled once at package level and not for every mailbox
+func BenchmarkParseAddressList(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		for _, input := range benchInputs {
+			if _, err := ParseAddressList(input); err != nil {
+				b.Fatalf("ParseAddressList(%q): %v", input, err)
+			}
+		}
+	}
+}
+
+// benchTestFileLines is the number of lines of the generated test file of BenchmarkParseTestFile
+const benchTestFileLines = 10000
+
+// BenchmarkParseTestFile parses a generated test file of 10k lines(the benchInputs repeated) line by line,
+// like the test file mode of the application
+// The compiled-per-mailbox baseline compiles the patterns of parseDisplayNameAndEmail for every line,
+// as the parser did before they were moved to package level
+func BenchmarkParseTestFile(b *testing.B) {
+
+	var sb strings.Builder
+	for i := 0; i < benchTestFileLines; i++ {
+		sb.WriteString(benchInputs[i%len(benchInputs)])
+		sb.WriteByte('\n')
+	}
+	testFile := sb.String()
+
+	patterns := []*regexp.Regexp{bracketRe, bareNameEmailRe, bracketOnlyRe, emailRe, bracketNameRe}
+
+	run := func(b *testing.B, compile bool) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			scanner := bufio.NewScanner(strings.NewReader(testFile))
+			for scanner.Scan() {
+				if compile {
+					for _, re := range patterns {
+						regexp.MustCompile(re.String())
+					}
+				}
+				if _, err := ParseAddressList(scanner.Text()); err != nil {
+					b.Fatalf("ParseAddressList(%q): %v", scanner.Text(), err)
+				}
+			}
+		}
+	}
+
+	b.Run("compiled-per-mailbox", func(b *testing.B) { run(b, true) })
+	b.Run("package-level", func(b *testing.B) { run(b, false) })
+}
+
+// BenchmarkParseAddressLongInput parses a pathological input of many "@" and quotes,
+// guarded it is rejected with ErrTooLong by the MaxLength check before the patterns are matched
+func BenchmarkParseAddressLongInput(b *testing.B) {
+
+	input := `"` + strings.Repeat(`a@"@`, 64*1024) + ` <a@x.com>`
+
+	b.Run("guarded", func(b *testing.B) {
+		b.ReportAllocs()
+		parser := &Parser{}
+		for i := 0; i < b.N; i++ {
+			if _, err := parser.ParseAddressList(input); !errors.Is(err, ErrTooLong) {
+				b.Fatalf("error = %v, want %v", err, ErrTooLong)
+			}
+		}
+	})
+
+	b.Run("unguarded", func(b *testing.B) {
+		b.ReportAllocs()
+		parser := &Parser{MaxLength: len(input) + 1}
+		for i := 0; i < b.N; i++ {
+			parser.ParseAddressList(input)
+		}
+	})
+}
@@ -0,0 +1,129 @@
+package emlparse
+
+import (
+	"strings"
+)
+
+// collect the text of the comments in "()" of a string
+// Nested comments are flattened in order of appearance, comments inside quoted strings are ignored
+//
+// Returns the text of all comments joined with a space
+// and the text of the comment at the end of the string(after the addr-spec), if there is one
+func collectComments(s string) (string, string) {
+
+	var comments []string
+	var sb strings.Builder
+	inQuote := false
+	depth := 0
+	trailing := ""
+
+	for i := 0; i < len(s); i++ {
+		char := s[i]
+
+		switch {
+		case char == '\\' && i+1 < len(s):
+			i++
+			if depth > 0 {
+				sb.WriteByte(s[i])
+			}
+		case char == '"' && depth == 0:
+			inQuote = !inQuote
+		case inQuote:
+		case char == '(':
+			if depth > 0 {
+				sb.WriteByte(' ')
+			}
+			depth++
+		case char == ')' && depth > 0:
+			depth--
+			if depth > 0 {
+				sb.WriteByte(' ')
+				continue
+			}
+
+			comment := strings.Join(strings.Fields(sb.String()), " ")
+			sb.Reset()
+			if comment == "" {
+				continue
+			}
+			comments = append(comments, comment)
+
+			trailing = ""
+			if strings.TrimSpace(s[i+1:]) == "" {
+				trailing = comment
+			}
+		case depth > 0:
+			sb.WriteByte(char)
+		}
+	}
+
+	return strings.Join(comments, " "), trailing
+}
+
+// check that every comment "(" of a string is closed by a ")" and there is no ")" without a "("
+// Parentheses inside quoted strings and escaped with a backslash are not counted
+//
+// Returns false if the parentheses are unbalanced
+func commentsBalanced(s string) bool {
+
+	inQuote := false
+	depth := 0
+
+	for i := 0; i < len(s); i++ {
+		char := s[i]
+
+		switch {
+		case char == '\\' && i+1 < len(s):
+			i++
+		case char == '"' && depth == 0:
+			inQuote = !inQuote
+		case inQuote:
+		case char == '(':
+			depth++
+		case char == ')':
+			if depth == 0 {
+				return false
+			}
+			depth--
+		}
+	}
+
+	return depth == 0
+}
+
+// remove the comments in "()" of a string, nested comments included
+// Unlike removeNestedComments, parentheses inside quoted strings are kept
+//
+// Returns the string without comments
+func stripComments(s string) string {
+
+	var sb strings.Builder
+	inQuote := false
+	depth := 0
+
+	for i := 0; i < len(s); i++ {
+		char := s[i]
+
+		switch {
+		case char == '\\' && i+1 < len(s):
+			if depth == 0 {
+				sb.WriteByte(char)
+				sb.WriteByte(s[i+1])
+			}
+			i++
+		case char == '"' && depth == 0:
+			inQuote = !inQuote
+			sb.WriteByte(char)
+		case inQuote:
+			sb.WriteByte(char)
+		case char == '(':
+			depth++
+		case char == ')' && depth > 0:
+			depth--
+		case depth == 0:
+			sb.WriteByte(char)
+		}
+	}
+
+	return strings.TrimSpace(sb.String())
+}
@@ -0,0 +1,171 @@
+package emlparse
+
+import (
+	"encoding/base64"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// encodedWordRe matches a MIME encoded-word, =?charset?encoding?text?=
+// The text can contain the whitespace of a header folded inside the word, it is removed before decoding
+var encodedWordRe = regexp.MustCompile(`=\?([^?\s]+)\?([bBqQ])\?([^?]*)\?=`)
+
+// DecodeHeader decodes the MIME encoded-words (RFC 2047) in an unfolded header value, e.g. a Subject:
+// A byte sequence that is not valid UTF-8 after decoding is replaced with U+FFFD
+//
+// Returns the decoded header value
+func DecodeHeader(value string) string {
+	return strings.ToValidUTF8(decodeEncodedWord(value), "\uFFFD")
+}
+
+// decode the MIME encoded-words (RFC 2047) in a string, e.g. =?UTF-8?B?SsO2cmc=?=
+// Adjacent encoded-words are concatenated without the whitespace between them,
+// the bytes of adjacent words with the same charset are decoded together,
+// so a multi-byte character split over two words is not broken
+// The whitespace of a header folded inside an encoded-word is removed from its text
+//
+// Returns the decoded string, words that can not be decoded are left as they are
+func decodeEncodedWord(str string) string {
+
+	matches := encodedWordRe.FindAllStringSubmatchIndex(str, -1)
+	if matches == nil {
+		return str
+	}
+
+	var sb strings.Builder
+	var pending []byte //the bytes of the adjacent encoded-words decoded so far
+	charset := ""      //the charset of the pending bytes, empty when there are none
+	last := 0
+
+	flush := func() {
+		sb.WriteString(decodeCharset(charset, pending))
+		pending, charset = nil, ""
+	}
+
+	for _, m := range matches {
+		gap := str[last:m[0]]
+		wordCharset := normalizeCharset(str[m[2]:m[3]])
+		raw, ok := decodeText(str[m[4]:m[5]], str[m[6]:m[7]])
+		ok = ok && knownCharset(wordCharset)
+
+		//whitespace between two adjacent encoded-words is not part of the text
+		adjacent := ok && charset != "" && strings.TrimSpace(gap) == ""
+		if !adjacent || wordCharset != charset {
+			flush()
+		}
+		if !adjacent {
+			sb.WriteString(gap)
+		}
+
+		if ok {
+			charset = wordCharset
+			pending = append(pending, raw...)
+		} else {
+			sb.WriteString(str[m[0]:m[1]])
+		}
+
+		last = m[1]
+	}
+	flush()
+	sb.WriteString(str[last:])
+
+	return sb.String()
+}
+
+// decode the text of a single encoded-word to bytes using its encoding("B" or "Q")
+// The text of a valid encoded-word has no whitespace, a space or tab left by unfolding
+// a header folded inside the word(e.g. =?UTF-8?B?SsO8cmdl bg==?=) is removed
+//
+// Returns the bytes in the charset of the word and false if the encoding is not supported or the text is malformed
+func decodeText(encoding string, text string) ([]byte, bool) {
+
+	text = strings.Join(strings.Fields(text), "")
+
+	var raw []byte
+
+	switch strings.ToUpper(encoding) {
+	case "B":
+		data, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, false
+		}
+		raw = data
+	case "Q":
+		for i := 0; i < len(text); i++ {
+			char := text[i]
+
+			if char == '_' {
+				//"_" always represents the space character(0x20), a literal underscore is encoded as =5F
+				raw = append(raw, ' ')
+			} else if char == '=' && i+2 < len(text) {
+				b, err := strconv.ParseUint(text[i+1:i+3], 16, 8)
+				if err != nil {
+					return nil, false
+				}
+				raw = append(raw, byte(b))
+				i += 2
+			} else {
+				raw = append(raw, char)
+			}
+		}
+	default:
+		return nil, false
+	}
+
+	return raw, true
+}
+
+// convert the charset name of an encoded-word to lowercase without the language suffix,
+// the charset can carry a language suffix, e.g. "UTF-8*en"(RFC 2231)
+//
+// Returns the charset name, e.g. utf-8
+func normalizeCharset(charset string) string {
+	charset, _, _ = strings.Cut(strings.ToLower(charset), "*")
+	return charset
+}
+
+// find the encoding of a charset name, the WHATWG labels(e.g. windows-1252, iso-8859-15, koi8-r, shift_jis)
+// and the IANA MIME names of every charset golang.org/x/text supports
+//
+// Returns the encoding and false if the charset is not supported
+func charsetEncoding(charset string) (encoding.Encoding, bool) {
+	if enc, err := htmlindex.Get(charset); err == nil {
+		return enc, true
+	}
+	if enc, err := ianaindex.MIME.Encoding(charset); err == nil && enc != nil {
+		return enc, true
+	}
+	return nil, false
+}
+
+// check if the bytes of a charset can be converted to UTF-8
+//
+// Returns true for a charset supported by charsetEncoding
+func knownCharset(charset string) bool {
+	_, ok := charsetEncoding(charset)
+	return ok
+}
+
+// convert the bytes of a known charset to UTF-8
+// A byte sequence that is not valid in the charset is replaced with U+FFFD
+//
+// Returns the UTF-8 text, empty for no bytes
+func decodeCharset(charset string, raw []byte) string {
+
+	if len(raw) == 0 {
+		return ""
+	}
+
+	if enc, ok := charsetEncoding(charset); ok {
+		if text, err := enc.NewDecoder().Bytes(raw); err == nil {
+			raw = text
+		}
+	}
+
+	return strings.ToValidUTF8(string(raw), "\uFFFD")
+}
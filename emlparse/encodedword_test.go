@@ -0,0 +1,71 @@
+package emlparse
+
+import "testing"
+
+func TestDecodeEncodedWordCharsets(t *testing.T) {
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "=?UTF-8?B?SsO8cmdlbg==?=", want: "Jürgen"},
+		{input: "=?us-ascii?Q?plain?=", want: "plain"},
+		{input: "=?ISO-8859-1?Q?M=FCller?=", want: "Müller"},
+		{input: "=?windows-1252?Q?=93Smart=94?=", want: "“Smart”"},
+		{input: "=?iso-8859-15?Q?=A4uro?=", want: "€uro"},
+		{input: "=?koi8-r?B?8NLJ18XU?=", want: "Привет"},
+		{input: "=?UTF-8*en?Q?J=C3=BCrgen?=", want: "Jürgen"},
+		{input: "=?x-unknown?Q?a?=", want: "=?x-unknown?Q?a?="},
+	}
+
+	for _, tt := range tests {
+		if got := decodeEncodedWord(tt.input); got != tt.want {
+			t.Errorf("decodeEncodedWord(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeEncodedWordQUnderscore(t *testing.T) {
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "=?UTF-8?Q?John_Doe?=", want: "John Doe"},
+		{input: "=?UTF-8?Q?John=20Doe?=", want: "John Doe"},
+		{input: "=?UTF-8?Q?John=5FDoe?=", want: "John_Doe"},
+		{input: "=?UTF-8?q?a_b=5Fc?=", want: "a b_c"},
+		{input: "=?ISO-8859-1?Q?J=F6rg_=5F_M=FCller?=", want: "Jörg _ Müller"},
+	}
+
+	for _, tt := range tests {
+		if got := decodeEncodedWord(tt.input); got != tt.want {
+			t.Errorf("decodeEncodedWord(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeEncodedWordFolded(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{name: "base64 folded in the middle of the text", input: "=?UTF-8?B?SsO8cmdlbiBN\r\n w7xsbGVy?= <j@x.com>", want: "Jürgen Müller"},
+		{name: "base64 folded with a tab", input: "=?UTF-8?B?SsO8cmdl\r\n\tbg==?= <j@x.com>", want: "Jürgen"},
+		{name: "character split over two folded words", input: "=?UTF-8?B?SsM=?=\r\n =?UTF-8?B?vHJnZW4=?= <j@x.com>", want: "Jürgen"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, err := ParseAddress(tt.input)
+			if err != nil {
+				t.Fatalf("ParseAddress(%q) unexpected error: %v", tt.input, err)
+			}
+			if address.DisplayName != tt.want || address.AddrSpec != "j@x.com" {
+				t.Errorf("ParseAddress(%q) = %q <%s>, want %q <j@x.com>", tt.input, address.DisplayName, address.AddrSpec, tt.want)
+			}
+		})
+	}
+}
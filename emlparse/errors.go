@@ -0,0 +1,68 @@
+package emlparse
+
+import "errors"
+
+// validation errors returned by ParseAddress and ParseAddressList
+// Callers can use errors.Is to branch on the specific validation failure
+var (
+	ErrNestedBrackets    = errors.New("nested < .. > not allowed as part of addr-spec")
+	ErrUnbalancedAngle   = errors.New("unbalanced angle brackets")
+	ErrMissingDomain     = errors.New("missing @ domain")
+	ErrNoAddrSpec        = errors.New("no addr-spec found")
+	ErrLocalpartDot      = errors.New("RFC 5322 forbids the localpart (what comes before the last @ in addr-spec) from ending in a dot")
+	ErrMultipleAddrs     = errors.New("more than one addr-spec given")
+	ErrUnterminatedQuote = errors.New("unterminated quoted part")
+	ErrUnknownTLD        = errors.New("unknown TLD")
+	ErrInvalidIDN        = errors.New("invalid internationalized domain name")
+	ErrNotGroup          = errors.New("not a group, the \"name:\" part is missing")
+	ErrNestedGroup       = errors.New("group inside a group is not allowed")
+	ErrEmptyGroupName    = errors.New("group without a display name")
+	ErrControlChar       = errors.New("control character in header")
+	ErrHeaderInjection   = errors.New("possible header injection")
+	ErrUnbalancedComment = errors.New("unbalanced comment parentheses")
+	ErrTooLong           = errors.New("address too long")
+	ErrLocalPartLength   = errors.New("local-part exceeds 64 octets")
+	ErrDomainLength      = errors.New("domain exceeds 255 octets")
+	ErrAddrSpecLength    = errors.New("addr-spec exceeds 254 octets")
+	ErrNoMatch           = errors.New("could not parse address")
+	ErrWarning           = errors.New("warning treated as error")
+
+	// ErrHeaderMissing is wrapped with the name of the header, e.g. "From" header missing or value is empty
+	ErrHeaderMissing = errors.New("header missing or value is empty")
+)
+
+// errors of LocateHeaders, the email has no header section to search
+var (
+	ErrEmptyHeaders = errors.New("empty header section")
+	ErrEmptyInput   = errors.New("empty input")
+	ErrLineTooLong  = errors.New("header line too long")
+)
+
+// PositionError is a validation error with the byte offset in the unfolded header value
+// where the problem was detected, e.g. the offending "@" or angle bracket
+// errors.Is still matches the wrapped validation error
+type PositionError struct {
+	Err      error
+	Position int
+}
+
+// Error returns the message of the wrapped validation error
+func (e *PositionError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the wrapped validation error
+func (e *PositionError) Unwrap() error {
+	return e.Err
+}
+
+// move the position of a PositionError by offset, the position of the item in the whole header
+//
+// Returns the error with the moved position or err unchanged if it has no position
+func withOffset(err error, offset int) error {
+	var perr *PositionError
+	if errors.As(err, &perr) {
+		return &PositionError{Err: perr.Err, Position: perr.Position + offset}
+	}
+	return err
+}
@@ -0,0 +1,28 @@
+package emlparse
+
+import (
+	"strings"
+)
+
+// characters of a display name that require it to be a quoted string(RFC 5322 specials)
+const specials = `()<>[]:;@\,."`
+
+// FormatAddress builds a RFC 5322 mailbox from a display name and an email, e.g. "Doe, John" <j@x.com>
+// The display name is quoted when it contains specials like commas, angle brackets, dots or quotes
+//
+// Returns the formatted mailbox or just <email> when the display name is empty
+func FormatAddress(name string, email string) string {
+
+	name = strings.TrimSpace(name)
+	email = strings.TrimSpace(email)
+	if name == "" {
+		return "<" + email + ">"
+	}
+
+	if strings.ContainsAny(name, specials) {
+		escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(name)
+		name = `"` + escaped + `"`
+	}
+
+	return name + " <" + email + ">"
+}
@@ -0,0 +1,116 @@
+package emlparse
+
+import (
+	"strings"
+)
+
+// Group structure contains a group of addresses(RFC 5322 group syntax), e.g.
+// Managers: alice@x.com, bob@y.com;
+// An empty group like "Undisclosed recipients:;" has no members
+type Group struct {
+	Name    string
+	Members []Address
+}
+
+// ParseGroup extracts the group name and the member addresses from a group string
+//
+// Returns an error if the string is not a group or one of the members does not pass the validation
+func ParseGroup(input string) (Group, error) {
+	return (&Parser{}).ParseGroup(input)
+}
+
+// ParseGroup extracts the group name and the member addresses from a group string
+// using the options of the parser
+//
+// Returns an error if the string is not a group, the group has no name or one of the members does not pass the validation
+func (p *Parser) ParseGroup(input string) (Group, error) {
+
+	if err := checkControlChars(input); err != nil {
+		return Group{}, err
+	}
+	input = unfoldHeader(input)
+
+	name, members, ok := splitGroup(strings.TrimSpace(input))
+	if !ok {
+		return Group{}, ErrNotGroup
+	}
+
+	//RFC 5322 requires a display-name before the colon, ":;" is not a group
+	if strings.TrimSpace(name) == "" {
+		return Group{}, ErrEmptyGroupName
+	}
+
+	if err := checkHeaderInjection(name); err != nil {
+		return Group{}, err
+	}
+
+	group := Group{Name: name, Members: []Address{}}
+	cursor := 0
+
+	for _, item := range splitAddressList(members) {
+		offset := itemOffset(input, item, &cursor)
+
+		//RFC 5322 does not allow a group inside a group
+		if _, _, ok := splitGroup(item); ok {
+			return Group{}, ErrNestedGroup
+		}
+
+		address, err := p.parseMailbox(item)
+		if err != nil {
+			return Group{}, withOffset(err, offset)
+		}
+		address.Group = name
+		group.Members = append(group.Members, address)
+	}
+
+	return group, nil
+}
+
+// split a group string "name: member, member;" into the name and the member list
+// The colon is only searched outside quoted strings, comments, angle brackets and domain-literals
+//
+// Returns the decoded group name, the member list and false if the string is not a group
+func splitGroup(s string) (string, string, bool) {
+
+	inQuote := false
+	depth := 0
+	angles := 0
+	literal := false
+
+	for i := 0; i < len(s); i++ {
+		char := s[i]
+
+		switch {
+		case char == '\\' && i+1 < len(s):
+			i++
+		case char == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case char == '(':
+			depth++
+		case char == ')' && depth > 0:
+			depth--
+		case depth > 0:
+		case char == '[':
+			literal = true
+		case char == ']':
+			literal = false
+		case literal:
+		case char == '<':
+			angles++
+		case char == '>' && angles > 0:
+			angles--
+		case char == ':' && angles == 0 && isMailtoColon(s, i):
+			i = mailtoEnd(s, i) - 1
+		case char == ':' && angles == 0:
+			name := strings.TrimSpace(removeNestedComments(s[:i]))
+			name = strings.Trim(name, `"`)
+			members := strings.TrimSpace(s[i+1:])
+			members = strings.TrimSuffix(members, ";")
+
+			return decodeEncodedWord(name), members, true
+		}
+	}
+
+	return "", "", false
+}
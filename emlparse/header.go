@@ -0,0 +1,152 @@
+package emlparse
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+// maximum length of a single header line, longer than the 64KB default of bufio.Scanner
+const maxHeaderLineLength = 1024 * 1024
+
+// DecodeInput wraps a reader so that a Windows exported email is read as UTF-8
+// A leading UTF-8 BOM is removed, UTF-16 (LE or BE, with or without a BOM) is decoded to UTF-8
+// Without a BOM, UTF-16 is detected from the NUL byte of the first ASCII character of the header
+//
+// Returns a reader with the UTF-8 content, any other input is returned unchanged
+func DecodeInput(r io.Reader) io.Reader {
+
+	br := bufio.NewReader(r)
+	start, _ := br.Peek(2)
+
+	var decoder transform.Transformer = encoding.Nop.NewDecoder()
+	if len(start) == 2 {
+		switch {
+		case start[0] != 0 && start[1] == 0:
+			decoder = unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewDecoder()
+		case start[0] == 0 && start[1] != 0:
+			decoder = unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM).NewDecoder()
+		}
+	}
+
+	//a BOM(UTF-8, UTF-16 LE or BE) overrides the detected encoding and is removed
+	return transform.NewReader(br, unicode.BOMOverride(decoder))
+}
+
+// LocateHeaders finds the values of the headers(e.g. "From", "Sender") in an email
+// Only the header section is searched, it ends at the first blank line and the body is never scanned
+// A folded header (continuation lines starting with a space or a tab) is unfolded into a single line
+// The whole field name before the colon must match, case-insensitive, so "From-Original:" is not taken for "From:"
+// When a header appears several times, the first(topmost) one is used
+// The reader is read as it is, wrap it with DecodeInput for a BOM or UTF-16
+//
+// Returns ErrEmptyInput if the input is empty, ErrEmptyHeaders if the header section is empty, the read error
+// or the trimmed values of the located headers, keyed by the names as they are passed
+func LocateHeaders(r io.Reader, names []string) (map[string]string, error) {
+
+	values := make(map[string]string)
+	current := ""
+	headerLines := 0
+	content := false
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxHeaderLineLength)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) != "" {
+			content = true
+		}
+
+		//continuation line of the folded header, CRLF + leading whitespace is replaced with a single space
+		if strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t") {
+			if current != "" {
+				values[current] += " " + strings.TrimLeft(line, " \t")
+			}
+			continue
+		}
+
+		//line == "" handles both cases transparently because bufio.Scanner automatically strips \r\n(Windows) or \n(Linux/macOS)
+		if line == "" {
+			if headerLines == 0 {
+				return nil, emptyHeadersError(scanner, content)
+			}
+			break
+		}
+
+		headerLines++
+		current = ""
+
+		name, rest, ok := CutHeaderField(line)
+		if !ok {
+			continue
+		}
+		for _, wanted := range names {
+			if strings.EqualFold(name, wanted) {
+				if _, seen := values[wanted]; !seen {
+					values[wanted] = rest
+					current = wanted
+				}
+				break
+			}
+		}
+	}
+
+	//a read error must not be reported as a missing header
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, ErrLineTooLong
+		}
+		return nil, err
+	}
+
+	if !content {
+		return nil, ErrEmptyInput
+	}
+
+	for name, value := range values {
+		values[name] = strings.TrimSpace(value)
+	}
+
+	return values, nil
+}
+
+// tell an email without a header section from an empty one, the rest of the input is scanned for a body
+// Only called for an email that starts with a blank line, so the body of a valid email is never read
+//
+// Returns ErrEmptyHeaders if there is a body, ErrEmptyInput if the rest is blank or the read error
+func emptyHeadersError(scanner *bufio.Scanner, content bool) error {
+
+	for !content && scanner.Scan() {
+		content = strings.TrimSpace(scanner.Text()) != ""
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !content {
+		return ErrEmptyInput
+	}
+
+	return ErrEmptyHeaders
+}
+
+// CutHeaderField splits a header line at the first colon into the field name and the value,
+// the name is not limited to a length, e.g. "Reply-To" and "From" are both cut at their own colon
+// The whitespace after the colon(a space or a tab, e.g. "From:\tAlice <a@x.com>") is not part of the value
+// and the whitespace before it is not part of the name, RFC 5322 obsolete syntax allows "From : a@x.com"
+//
+// Returns the field name, the value and false if the line has no colon
+func CutHeaderField(line string) (string, string, bool) {
+
+	name, value, ok := strings.Cut(line, ":")
+	if !ok {
+		return "", "", false
+	}
+
+	return strings.TrimRight(name, " \t"), strings.TrimLeft(value, " \t"), true
+}
@@ -0,0 +1,146 @@
+package emlparse
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestCutHeaderField(t *testing.T) {
+
+	tests := []struct {
+		line  string
+		name  string
+		value string
+		ok    bool
+	}{
+		{line: "From: a@x.com", name: "From", value: "a@x.com", ok: true},
+		{line: "From:\ta@x.com", name: "From", value: "a@x.com", ok: true},
+		{line: "From : a@x.com", name: "From", value: "a@x.com", ok: true},
+		{line: "From\t:a@x.com", name: "From", value: "a@x.com", ok: true},
+		{line: "From-X : a@x.com", name: "From-X", value: "a@x.com", ok: true},
+		{line: "no colon here"},
+	}
+
+	for _, tt := range tests {
+		name, value, ok := CutHeaderField(tt.line)
+		if name != tt.name || value != tt.value || ok != tt.ok {
+			t.Errorf("CutHeaderField(%q) = %q, %q, %v, want %q, %q, %v", tt.line, name, value, ok, tt.name, tt.value, tt.ok)
+		}
+	}
+}
+
+func TestLocateHeadersExactName(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+		found  bool
+	}{
+		{name: "exact name", header: "From: a@x.com", want: "a@x.com", found: true},
+		{name: "other case", header: "FROM: a@x.com", want: "a@x.com", found: true},
+		{name: "longer name", header: "From-Original: a@x.com"},
+		{name: "name as prefix", header: "Frombidden: a@x.com"},
+		{name: "Return-Path near-miss", header: "Return-Path-Original: <a@x.com>"},
+		{name: "X- prefix", header: "X-From: a@x.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := LocateHeaders(strings.NewReader(tt.header+"\r\nSubject: hi\r\n\r\nbody\r\n"), []string{"From", "Return-Path"})
+			if err != nil {
+				t.Fatalf("LocateHeaders unexpected error: %v", err)
+			}
+			value, found := values["From"]
+			if found != tt.found || value != tt.want {
+				t.Errorf("LocateHeaders From = %q, %v, want %q, %v", value, found, tt.want, tt.found)
+			}
+			if _, found := values["Return-Path"]; found {
+				t.Errorf("LocateHeaders found Return-Path in %q", tt.header)
+			}
+		})
+	}
+}
+
+func TestLocateHeadersEmptyInput(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		wantErr error
+	}{
+		{name: "zero bytes", input: "", wantErr: ErrEmptyInput},
+		{name: "whitespace only", input: " \r\n\t\r\n\r\n", wantErr: ErrEmptyInput},
+		{name: "blank lines only", input: "\r\n\r\n", wantErr: ErrEmptyInput},
+		{name: "UTF-8 BOM only", input: "\xef\xbb\xbf", wantErr: ErrEmptyInput},
+		{name: "UTF-8 BOM and whitespace", input: "\xef\xbb\xbf \r\n", wantErr: ErrEmptyInput},
+		{name: "body without header section", input: "\r\nbody\r\n", wantErr: ErrEmptyHeaders},
+		{name: "From line without line end", input: "From: a@x.com", want: "a@x.com"},
+		{name: "From line without blank line", input: "From: a@x.com\r\n", want: "a@x.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := LocateHeaders(DecodeInput(strings.NewReader(tt.input)), []string{"From"})
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("LocateHeaders error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LocateHeaders unexpected error: %v", err)
+			}
+			if values["From"] != tt.want {
+				t.Errorf("LocateHeaders From = %q, want %q", values["From"], tt.want)
+			}
+		})
+	}
+}
+
+func TestLocateHeadersColonWhitespace(t *testing.T) {
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+		found  bool
+	}{
+		{name: "no whitespace", header: "From:a@x.com", want: "a@x.com", found: true},
+		{name: "space after the colon", header: "From: a@x.com", want: "a@x.com", found: true},
+		{name: "tab after the colon", header: "From:\ta@x.com", want: "a@x.com", found: true},
+		{name: "space before the colon", header: "From : a@x.com", want: "a@x.com", found: true},
+		{name: "tab before the colon", header: "From\t: a@x.com", want: "a@x.com", found: true},
+		{name: "longer name with space before the colon", header: "From-X : a@x.com"},
+		{name: "mbox separator line", header: "From a@x.com Mon Jan  1 00:00:00 2024"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := LocateHeaders(strings.NewReader(tt.header+"\r\nSubject: hi\r\n\r\nbody\r\n"), []string{"From"})
+			if err != nil {
+				t.Fatalf("LocateHeaders unexpected error: %v", err)
+			}
+			value, found := values["From"]
+			if found != tt.found || value != tt.want {
+				t.Errorf("LocateHeaders From = %q, %v, want %q, %v", value, found, tt.want, tt.found)
+			}
+		})
+	}
+}
+
+func TestLocateHeadersSkipsBody(t *testing.T) {
+
+	input := "Subject: hi\r\n\r\nFrom : body@x.com\r\nFrom: body@x.com\r\n"
+
+	values, err := LocateHeaders(strings.NewReader(input), []string{"From"})
+	if err != nil {
+		t.Fatalf("LocateHeaders unexpected error: %v", err)
+	}
+	if value, found := values["From"]; found {
+		t.Errorf("LocateHeaders matched the body line %q", value)
+	}
+}
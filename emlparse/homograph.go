@@ -0,0 +1,95 @@
+package emlparse
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/net/idna"
+)
+
+// scripts that are checked for mixing in a domain label
+var homographScripts = []struct {
+	name  string
+	table *unicode.RangeTable
+}{
+	{"Latin", unicode.Latin},
+	{"Cyrillic", unicode.Cyrillic},
+	{"Greek", unicode.Greek},
+	{"Armenian", unicode.Armenian},
+	{"Cherokee", unicode.Cherokee},
+}
+
+// latinConfusables maps Cyrillic and Greek letters to the Latin letters they look like
+var latinConfusables = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c', 'т': 't',
+	'у': 'y', 'х': 'x', 'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'ӏ': 'l', 'ԛ': 'q', 'ԝ': 'w', 'һ': 'h',
+	'α': 'a', 'ε': 'e', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'τ': 't', 'υ': 'u', 'χ': 'x',
+}
+
+// check the domain of an addr-spec for homographs, labels that can be mistaken for another domain
+// A label that mixes scripts(paypal with a Cyrillic "а") or that only has letters that look like Latin
+// ones(аррӏе in Cyrillic) is reported, a punycode label is decoded first
+//
+// Returns the warning or an empty string if the domain looks safe
+func checkHomograph(addrSpec string) string {
+
+	if isDomainLiteral(addrSpec) {
+		return ""
+	}
+
+	domain, err := idna.ToUnicode(addrSpec[strings.LastIndex(addrSpec, "@")+1:])
+	if err != nil {
+		return ""
+	}
+
+	for _, label := range strings.Split(domain, ".") {
+		scripts := labelScripts(label)
+		if len(scripts) > 1 {
+			return fmt.Sprintf("mixed scripts in domain label %q: %s", label, strings.Join(scripts, ", "))
+		}
+
+		if len(scripts) == 1 && scripts[0] != "Latin" {
+			if latin, ok := latinLookalike(label); ok {
+				return fmt.Sprintf("domain label %q can be confused with %q", label, latin)
+			}
+		}
+	}
+
+	return ""
+}
+
+// find the scripts of the letters of a domain label, digits and the hyphen have no script
+//
+// Returns the names of the scripts, in the order of homographScripts
+func labelScripts(label string) []string {
+
+	scripts := []string{}
+	for _, script := range homographScripts {
+		if strings.ContainsFunc(label, func(r rune) bool { return unicode.Is(script.table, r) }) {
+			scripts = append(scripts, script.name)
+		}
+	}
+
+	return scripts
+}
+
+// spell a domain label with the Latin letters its letters look like
+//
+// Returns the Latin spelling and false if one of the letters does not look like a Latin one
+func latinLookalike(label string) (string, bool) {
+
+	var sb strings.Builder
+	for _, r := range strings.ToLower(label) {
+		if latin, ok := latinConfusables[r]; ok {
+			sb.WriteRune(latin)
+			continue
+		}
+		if unicode.IsLetter(r) {
+			return "", false
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String(), true
+}
@@ -0,0 +1,27 @@
+package emlparse
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// convert the domain of an addr-spec to its ASCII(punycode) form, e.g. 用户@例え.jp -> 用户@xn--r8jz45g.jp
+// Already ASCII domains are returned unchanged, only lowercased by the IDNA mapping
+//
+// Returns ErrInvalidIDN if the domain is not a valid internationalized domain name
+func toASCIIAddrSpec(addrSpec string) (string, error) {
+
+	at := strings.LastIndex(addrSpec, "@")
+	if at < 0 {
+		return addrSpec, nil
+	}
+
+	domain, err := idna.Lookup.ToASCII(addrSpec[at+1:])
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidIDN, err)
+	}
+
+	return addrSpec[:at+1] + domain, nil
+}
@@ -0,0 +1,33 @@
+package emlparse
+
+import (
+	"regexp"
+	"strings"
+)
+
+// a quoted string immediately followed by the @ of the addr-spec, e.g. "john doe"@example.com
+var quotedLocalPartRe = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"\s*@`)
+
+// replace a quoted local-part(RFC 5322) in a mailbox with a plain placeholder
+// so that spaces, dots, "@" and escaped quotes inside it don't break the parsing
+//
+// Returns the mailbox with the placeholder, the unquoted local-part and false if there is no quoted local-part
+func extractQuotedLocalPart(mailbox string) (string, string, bool) {
+
+	m := quotedLocalPartRe.FindStringSubmatchIndex(mailbox)
+	if m == nil {
+		return mailbox, "", false
+	}
+
+	//remove the escaping backslash of quoted-pairs, e.g. \" -> "
+	var sb strings.Builder
+	local := mailbox[m[2]:m[3]]
+	for i := 0; i < len(local); i++ {
+		if local[i] == '\\' && i+1 < len(local) {
+			i++
+		}
+		sb.WriteByte(local[i])
+	}
+
+	return mailbox[:m[0]] + "x@" + mailbox[m[1]:], sb.String(), true
+}
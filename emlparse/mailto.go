@@ -0,0 +1,73 @@
+package emlparse
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// mailtoRe matches a mailto: URI with the optional query, e.g. mailto:a@x.com?subject=hi
+var mailtoRe = regexp.MustCompile(`(?i)\bmailto:([^\s<>?,;"]+)(?:\?[^\s<>"]*)?`)
+
+// replace a mailto: URI, copied from a link, with its address, e.g. mailto:a%40x.com?subject=hi -> a@x.com
+// The query is dropped and the percent-escapes of the address are decoded
+//
+// Returns the mailbox without the scheme and false if it has no mailto: URI,
+// ErrHeaderInjection if a decoded address contains a CR or LF, e.g. mailto:a%0D%0ABcc:x@y.com
+func stripMailto(mailbox string) (string, bool, error) {
+
+	if !mailtoRe.MatchString(mailbox) {
+		return mailbox, false, nil
+	}
+
+	var err error
+	mailbox = mailtoRe.ReplaceAllStringFunc(mailbox, func(uri string) string {
+		addr := mailtoRe.FindStringSubmatch(uri)[1]
+		if unescaped, uerr := url.PathUnescape(addr); uerr == nil {
+			addr = unescaped
+		}
+		if cerr := checkHeaderInjection(addr); cerr != nil {
+			err = cerr
+		}
+		return addr
+	})
+	if err != nil {
+		return "", false, err
+	}
+
+	return mailbox, true, nil
+}
+
+// check if the colon at index i of s is the colon of a mailto: scheme and not the colon of a group
+// The address follows the scheme directly, "mailto: a@x.com;" is a group named mailto,
+// a bare "mailto:" at the end is an empty URI and not an empty group
+//
+// Returns true for the colon of mailto:
+func isMailtoColon(s string, i int) bool {
+	start := i - len("mailto")
+	if start < 0 || !strings.EqualFold(s[start:i], "mailto") {
+		return false
+	}
+	if i+1 < len(s) && (s[i+1] == ' ' || s[i+1] == '\t') {
+		return false
+	}
+	return start == 0 || !isWordChar(s[start-1])
+}
+
+// find the end of the mailto: URI whose scheme colon is at index i of s
+// The URI ends at a whitespace, an angle bracket, a quote or a list separator
+//
+// Returns the index after the last byte of the URI
+func mailtoEnd(s string, i int) int {
+	if end := strings.IndexAny(s[i:], " \t<>\",;"); end >= 0 {
+		return i + end
+	}
+	return len(s)
+}
+
+// check if a byte is a letter, a digit or an underscore(a \w of a regex)
+//
+// Returns true for a word character
+func isWordChar(char byte) bool {
+	return char == '_' || char >= '0' && char <= '9' || char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z'
+}
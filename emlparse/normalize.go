@@ -0,0 +1,65 @@
+package emlparse
+
+import (
+	"strings"
+)
+
+// NormalizeAddress builds the canonical form of an addr-spec for comparison and deduplication
+// The domain is case-insensitive and is lowercased, the local-part is case-sensitive and is preserved
+// A domain-literal like [IPv6:2001:DB8::1] is left unchanged
+//
+// Returns the normalized addr-spec
+func NormalizeAddress(addrSpec string) string {
+
+	at := strings.LastIndex(addrSpec, "@")
+	if at < 0 {
+		return addrSpec
+	}
+
+	if isDomainLiteral(addrSpec) {
+		return addrSpec
+	}
+
+	return addrSpec[:at] + "@" + strings.ToLower(addrSpec[at+1:])
+}
+
+// SplitAddress splits an addr-spec into the local-part and the domain at the last "@"
+// An "@" of a quoted local-part("x@y"@z.com) is part of the local-part, the domain never contains one
+//
+// Returns the local-part and the domain, an empty domain if there is no "@"
+func SplitAddress(addrSpec string) (string, string) {
+
+	at := strings.LastIndex(addrSpec, "@")
+	if at < 0 {
+		return addrSpec, ""
+	}
+
+	return addrSpec[:at], addrSpec[at+1:]
+}
+
+// check if the domain of an addr-spec is a domain-literal, e.g. user@[192.168.1.1]
+//
+// Returns true for a domain in "[]"
+func isDomainLiteral(addrSpec string) bool {
+	domain := addrSpec[strings.LastIndex(addrSpec, "@")+1:]
+	return strings.HasPrefix(domain, "[") && strings.HasSuffix(domain, "]")
+}
+
+// smartPunctuation maps the Windows-1252 "smart" punctuation, e.g. from text pasted from a word processor,
+// to the ASCII equivalents
+var smartPunctuation = strings.NewReplacer(
+	"‘", "'", "’", "'", "‚", "'", "‛", "'",
+	"“", `"`, "”", `"`, "„", `"`, "‟", `"`,
+	"‹", "<", "›", ">",
+	"–", "-", "—", "-",
+	"…", "...",
+	"\u00a0", " ",
+)
+
+// NormalizePunctuation replaces the smart quotes, dashes, the ellipsis and the non-breaking space
+// of a display name with the ASCII equivalents, e.g. “O’Brien – Sales” becomes "O'Brien - Sales"
+//
+// Returns the normalized display name
+func NormalizePunctuation(name string) string {
+	return smartPunctuation.Replace(name)
+}
@@ -0,0 +1,50 @@
+package emlparse
+
+import (
+	"slices"
+	"strings"
+)
+
+// ParseReturnPath extracts the address from the value of a "Return-Path:" header, e.g. <bounce@x.com>
+// The null return path <> of a bounce message is not an error, it returns an address
+// with an empty AddrSpec and NullReturnPath set
+//
+// Returns an error if the value is not <> and does not pass the validation of a single mailbox
+func ParseReturnPath(input string) (Address, error) {
+	return (&Parser{}).ParseReturnPath(input)
+}
+
+// ParseReturnPath extracts the address from the value of a "Return-Path:" header
+// using the options of the parser
+//
+// A Return-Path: never has a display name, so it has no "missing display name" warning
+//
+// Returns an error if the value is not <> and does not pass the validation of a single mailbox
+func (p *Parser) ParseReturnPath(input string) (Address, error) {
+
+	if err := checkControlChars(input); err != nil {
+		return Address{}, err
+	}
+
+	//the null return path can be written with whitespace or comments, e.g. < > or <> (bounce)
+	value := strings.Join(strings.Fields(stripComments(unfoldHeader(input))), "")
+	if value == "<>" {
+		return Address{NullReturnPath: true}, nil
+	}
+
+	//the warnings are promoted after the display name warning is removed
+	lenient := *p
+	lenient.WarningsAsErrors = false
+
+	address, err := lenient.ParseAddress(input)
+	if err != nil {
+		return Address{}, err
+	}
+
+	address.Warnings = slices.DeleteFunc(address.Warnings, func(warning string) bool { return warning == WarnNoDisplayName })
+	if err := p.warningsError(address); err != nil {
+		return Address{}, err
+	}
+
+	return address, nil
+}
@@ -0,0 +1,127 @@
+package emlparse
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseStream reads the messages of a stream one by one and calls yield for every mailbox
+// of the "From:" header of every message, in the order they appear
+// The messages are separated by the mbox "From " lines(with a space, not a colon),
+// a stream without separator lines is a single message
+// Only the header of the current message is kept in memory, so the stream can be of any size
+// A stream with a BOM or in UTF-16 is decoded with DecodeInput, like a single email
+//
+// Returns void, a message with an invalid or missing "From:" header and a read error
+// are passed to yield as an error with an empty address
+func ParseStream(r io.Reader, yield func(Address, error)) {
+	(&Parser{}).ParseStream(r, yield)
+}
+
+// ParseStream reads the messages of a stream one by one and calls yield for every mailbox
+// of the "From:" header of every message using the options of the parser
+//
+// Returns void, a message with an invalid or missing "From:" header and a read error
+// are passed to yield as an error with an empty address
+func (p *Parser) ParseStream(r io.Reader, yield func(Address, error)) {
+
+	err := SplitMessages(DecodeInput(r), func(header string) {
+		//an empty header section is a missing header, the scanner is the one of the single email path
+		values, _ := LocateHeaders(strings.NewReader(header), []string{"From"})
+		value := values["From"]
+		if value == "" {
+			yield(Address{}, fmt.Errorf("%q %w", "From", ErrHeaderMissing))
+			return
+		}
+
+		addresses, err := p.ParseAddressList(value)
+		if err != nil {
+			yield(Address{}, err)
+			return
+		}
+		for _, address := range addresses {
+			yield(address, nil)
+		}
+	})
+
+	if err != nil {
+		yield(Address{}, err)
+	}
+}
+
+// SplitMessages reads the messages of a stream one by one and calls fn with the header section of every message
+// A line starting with "From " at the start of the stream or after a blank line is an mbox separator
+// and starts a new message, a "From:" or "From :" header is never taken for a separator
+// The header section is passed with "\n" line endings and without the separator line, the body is skipped
+//
+// Returns an error if the stream can not be read
+func SplitMessages(r io.Reader, fn func(header string)) error {
+
+	br := bufio.NewReader(r)
+
+	var header strings.Builder
+	started := false  //the current message has at least one line
+	inHeader := true  //the lines are in the header section of the current message
+	prevBlank := true //the previous line is blank, a separator is only accepted after a blank line
+
+	finish := func() {
+		if started {
+			fn(header.String())
+		}
+		header.Reset()
+		started, inHeader = false, true
+	}
+
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if line == "" && err != nil {
+			break
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if prevBlank && isMboxSeparator(line) {
+			finish()
+			prevBlank = false
+			continue
+		}
+		prevBlank = line == ""
+
+		//a blank line ends the header section, a message can also start with it(empty header section)
+		if inHeader {
+			if line == "" {
+				inHeader = false
+				if !started {
+					header.WriteString("\n")
+				}
+			} else {
+				header.WriteString(line + "\n")
+			}
+		}
+		started = true
+
+		if err != nil {
+			break
+		}
+	}
+
+	finish()
+	return nil
+}
+
+// check if a line is an mbox separator, "From " and the envelope sender, e.g. From a@x.com Mon Jan  1 00:00:00 2024
+// A "From :" header(whitespace before the colon) starts with "From " too, but its field name is From
+//
+// Returns true for a separator line
+func isMboxSeparator(line string) bool {
+	if !strings.HasPrefix(line, "From ") {
+		return false
+	}
+	name, _, ok := CutHeaderField(line)
+	return !ok || !strings.EqualFold(name, "From")
+}
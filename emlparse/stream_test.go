@@ -0,0 +1,46 @@
+package emlparse
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseStreamHeaderMissing(t *testing.T) {
+
+	var errs []error
+	ParseStream(strings.NewReader("Subject: hi\n\nbody\n"), func(_ Address, err error) {
+		errs = append(errs, err)
+	})
+
+	if len(errs) != 1 || !errors.Is(errs[0], ErrHeaderMissing) || errs[0].Error() != `"From" header missing or value is empty` {
+		t.Errorf("ParseStream errors = %v, want the wrapped ErrHeaderMissing", errs)
+	}
+}
+
+func TestParseStreamDecodesInput(t *testing.T) {
+
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "UTF-8 BOM", input: "\xef\xbb\xbfFrom: J\xc3\xbcrgen <j@x.com>\r\n\r\n"},
+		{name: "UTF-16 LE with BOM", input: "\xff\xfeF\x00r\x00o\x00m\x00:\x00 \x00J\x00\xfc\x00r\x00g\x00e\x00n\x00 \x00<\x00j\x00@\x00x\x00.\x00c\x00o\x00m\x00>\x00\r\x00\n\x00"},
+		{name: "folded header with whitespace before the colon", input: "From :\r\n J\xc3\xbcrgen <j@x.com>\r\n\r\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Address
+			ParseStream(strings.NewReader(tt.input), func(address Address, err error) {
+				if err != nil {
+					t.Fatalf("ParseStream unexpected error: %v", err)
+				}
+				got = append(got, address)
+			})
+			if len(got) != 1 || got[0].DisplayName != "Jürgen" || got[0].AddrSpec != "j@x.com" {
+				t.Errorf("ParseStream = %+v, want Jürgen <j@x.com>", got)
+			}
+		})
+	}
+}
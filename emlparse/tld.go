@@ -0,0 +1,52 @@
+package emlparse
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/idna"
+)
+
+// list of the known top level domains, one per line in upper case, the source and version are in the header of tlds.txt
+//
+//go:embed tlds.txt
+var tldList string
+
+var (
+	knownTLDs     map[string]bool
+	knownTLDsOnce sync.Once
+)
+
+// check the top level domain of an addr-spec against the bundled TLD list
+// The comparison is case-insensitive
+//
+// Returns ErrUnknownTLD if the TLD is not in the list
+func checkTLD(addrSpec string) error {
+
+	knownTLDsOnce.Do(func() {
+		knownTLDs = make(map[string]bool)
+		for _, line := range strings.Split(tldList, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			knownTLDs[strings.ToLower(line)] = true
+		}
+	})
+
+	tld := addrSpec[strings.LastIndex(addrSpec, ".")+1:]
+
+	//the list contains internationalized TLDs in their punycode form
+	lookup, err := idna.Lookup.ToASCII(tld)
+	if err != nil {
+		lookup = tld
+	}
+
+	if !knownTLDs[strings.ToLower(lookup)] {
+		return fmt.Errorf("%w: %s", ErrUnknownTLD, tld)
+	}
+
+	return nil
+}
@@ -0,0 +1,29 @@
+package emlparse
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckTLD(t *testing.T) {
+
+	tests := []struct {
+		addrSpec string
+		wantErr  error
+	}{
+		{addrSpec: "a@x.com"},
+		{addrSpec: "a@x.COM"},
+		{addrSpec: "a@x.co.za"},
+		{addrSpec: "a@x.xn--p1ai"},
+		{addrSpec: "a@x.рф"},
+		{addrSpec: "a@x.invalidtld", wantErr: ErrUnknownTLD},
+		//retired TLDs are not in the ICANN section of the Public Suffix List
+		{addrSpec: "a@x.alfaromeo", wantErr: ErrUnknownTLD},
+	}
+
+	for _, tt := range tests {
+		if err := checkTLD(tt.addrSpec); !errors.Is(err, tt.wantErr) {
+			t.Errorf("checkTLD(%q) error = %v, want %v", tt.addrSpec, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,321 @@
+package emlparse
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// quotedStringRe matches a quoted string with its escaped characters, e.g. "Embedded quote \" here"
+var quotedStringRe = regexp.MustCompile(`"(?:[^"\\]|\\.)*"`)
+
+// addrCountRe matches content within < > that contains an @ symbol or a bare email
+var addrCountRe = regexp.MustCompile(`<[^<>@]+@[^<>]+>|[^\s<>()@,;:]+@[^\s<>()@,;]+`)
+
+// embeddedAddrRe matches an address in angle brackets, e.g. <bill@gates.com>
+var embeddedAddrRe = regexp.MustCompile(`<[^<>]*@[^<>]*>`)
+
+// remove nested comments in a string if they exits
+//
+// Returns a string without comments in "()"
+func removeNestedComments(s string) string {
+
+	var sb strings.Builder
+	depth := 0
+
+	for i := 0; i < len(s); i++ {
+		char := s[i]
+
+		if char == '(' {
+			depth++
+		}
+
+		if depth == 0 {
+			sb.WriteByte(char)
+		}
+
+		if char == ')' && depth > 0 {
+			depth--
+		}
+	}
+
+	return strings.TrimSpace(sb.String())
+}
+
+// check the "From:" string for validation
+// Also makes some small transformation of the input string
+// The comments outside quoted strings are removed first
+// validates the from line against :
+// 1. nested <> in addr_spec, outside the quoted strings
+// 2. a "<" without ">" or a ">" without "<", outside the quoted strings
+// 3. missing @ domain
+// 4. no addr-spec found
+// 5. RFC 5322 forbids the localpart (what comes before the last @ in addr-spec, outside quoted strings) from ending in a dot
+// 6. more than one addr-spec given
+// 7. unterminated quoted part
+// 8. unbalanced comment parentheses
+//
+// Returns an error if the validation does not passes
+// or input string with small transformation for following analysis in detection
+func checkForErrors(str string) (string, error) {
+
+	str = strings.Trim(str, "\n\r")
+	orig := str
+
+	if !commentsBalanced(str) {
+		return "", ErrUnbalancedComment
+	}
+
+	//the comments are removed first, so a "@" or "<<" in a comment does not confuse the checks
+	str = stripComments(str)
+
+	//doubled angle brackets are only nested outside quoted strings, "<<VIP>>" <v@x.com> is a valid display name
+	unquoted := quotedStringRe.ReplaceAllString(str, "q")
+	brackets := strings.Contains(unquoted, ">>") || strings.Contains(unquoted, "<<")
+
+	if brackets {
+		pos := indexUnquoted(orig, "<<")
+		if closing := indexUnquoted(orig, ">>"); pos < 0 || (closing >= 0 && closing < pos) {
+			pos = closing
+		}
+		return "", &PositionError{Err: ErrNestedBrackets, Position: pos}
+	}
+
+	if pos := unbalancedAngle(orig); pos >= 0 {
+		return "", &PositionError{Err: ErrUnbalancedAngle, Position: pos}
+	}
+
+	//a "@" in a quoted string("a@b"@x.com) is not the domain separator, the last "@" outside of them is
+	at := strings.LastIndex(unquoted, "@")
+	if strings.Contains(str, "<") && at < 0 {
+		return "", ErrMissingDomain
+	}
+
+	if at < 0 {
+		return "", ErrNoAddrSpec
+	}
+
+	userName, domain := unquoted[:at], unquoted[at+1:]
+	if strings.HasPrefix(userName, ".") || strings.HasSuffix(userName, ".") || strings.HasPrefix(domain, ".") {
+		return "", ErrLocalpartDot
+	}
+
+	emailSplit := strings.Split(str, "\"")
+	if len(emailSplit) == 3 {
+		numEmails := countNoEmails(emailSplit[2])
+		if numEmails > 1 {
+			//the position is the "@" of the second address
+			pos := -1
+			if ats := indexesUnquoted(orig, "@"); len(ats) > 1 {
+				pos = ats[1]
+			}
+			return "", &PositionError{Err: ErrMultipleAddrs, Position: pos}
+		}
+	}
+
+	//an address embedded in the quoted display name("Bill G <bill@gates.com>") is removed like a comment
+	if len(emailSplit) > 1 && embeddedAddrRe.MatchString(emailSplit[1]) {
+		str = strings.Replace(str, "<", "(", 1)
+		str = strings.Replace(str, ">", ")", 1)
+		str = removeNestedComments(str)
+	}
+	noQuotes := 0
+	noQuotes = strings.Count(str, "\"")
+
+	noEscQuotes := strings.Count(str, "\\\"")
+	if noEscQuotes > 0 {
+		if noQuotes%2 == 0 {
+			str = strings.Replace(str, "\\\"", "", noEscQuotes)
+		}
+		noEscQuotes = noEscQuotes - 1
+		noQuotes = noQuotes - 1
+	}
+
+	if noEscQuotes%2 != 0 || noQuotes%2 != 0 {
+		return "", &PositionError{Err: ErrUnterminatedQuote, Position: unmatchedQuote(orig)}
+	}
+
+	return str, nil
+}
+
+// find the byte offsets of sub outside quoted strings and comments
+//
+// Returns the offsets in s, empty if sub is not found
+func indexesUnquoted(s string, sub string) []int {
+
+	var indexes []int
+	inQuote := false
+	depth := 0
+
+	for i := 0; i < len(s); i++ {
+		char := s[i]
+
+		switch {
+		case char == '\\' && i+1 < len(s):
+			i++
+		case char == '"' && depth == 0:
+			inQuote = !inQuote
+		case inQuote:
+		case char == '(':
+			depth++
+		case char == ')' && depth > 0:
+			depth--
+		case depth > 0:
+		case strings.HasPrefix(s[i:], sub):
+			indexes = append(indexes, i)
+		}
+	}
+
+	return indexes
+}
+
+// find the first byte offset of sub outside quoted strings and comments
+//
+// Returns the offset in s or -1 if sub is not found
+func indexUnquoted(s string, sub string) int {
+	if indexes := indexesUnquoted(s, sub); len(indexes) > 0 {
+		return indexes[0]
+	}
+	return -1
+}
+
+// find an angle bracket without its pair, outside quoted strings and comments
+// A "<" of "<<" is nested and not unbalanced, nesting is checked before
+//
+// Returns the offset of a ">" without "<" before it or of a "<" that is never closed, -1 if the brackets are balanced
+func unbalancedAngle(s string) int {
+
+	brackets := append(indexesUnquoted(s, "<"), indexesUnquoted(s, ">")...)
+	slices.Sort(brackets)
+
+	open := -1
+	for _, i := range brackets {
+		switch {
+		case s[i] == '<':
+			open = i
+		case open < 0:
+			return i
+		default:
+			open = -1
+		}
+	}
+
+	return open
+}
+
+// find the opening quote of a quoted string that is never closed
+//
+// Returns the offset of the opening quote, or of the last quote if every quoted string is closed
+func unmatchedQuote(s string) int {
+
+	open := -1
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && i+1 < len(s):
+			i++
+		case s[i] == '"' && open < 0:
+			open = i
+		case s[i] == '"':
+			open = -1
+		}
+	}
+
+	if open < 0 {
+		return strings.LastIndex(s, "\"")
+	}
+	return open
+}
+
+// check a header value for control characters, RFC 5322 does not allow them in a header field
+// Only the folding whitespace is allowed: a tab and CRLF followed by a space or a tab
+// This rejects header injection like "a@b.com\r\nBcc: c@d.com" and NUL bytes
+//
+// Returns ErrControlChar if the value contains a disallowed control character
+func checkControlChars(str string) error {
+
+	for i := 0; i < len(str); i++ {
+		char := str[i]
+
+		if char == '\t' {
+			continue
+		}
+
+		//a folded line, CRLF and a whitespace that starts the continuation line
+		if char == '\r' && i+2 < len(str) && str[i+1] == '\n' && (str[i+2] == ' ' || str[i+2] == '\t') {
+			i++
+			continue
+		}
+
+		if char < 0x20 || char == 0x7f {
+			return ErrControlChar
+		}
+	}
+
+	return nil
+}
+
+// check the length of an addr-spec against the limits of RFC 5321(SMTP),
+// local-part 64 octets, domain 255 octets and the whole addr-spec 254 octets
+//
+// Returns an error if one of the limits is exceeded
+func checkLengths(addrSpec string) error {
+
+	at := strings.LastIndex(addrSpec, "@")
+	if at < 0 {
+		return nil
+	}
+
+	switch {
+	case at > 64:
+		return ErrLocalPartLength
+	case len(addrSpec)-at-1 > 255:
+		return ErrDomainLength
+	case len(addrSpec) > 254:
+		return ErrAddrSpecLength
+	}
+
+	return nil
+}
+
+// check the extracted values for a CR or LF, e.g. from an encoded-word =?utf-8?Q?x=0D=0ABcc=3A_a=40b.c?=
+// Written to another header, the value would start a new header line(CRLF injection)
+//
+// Returns ErrHeaderInjection if one of the values contains a CR or LF
+func checkHeaderInjection(values ...string) error {
+	for _, value := range values {
+		if strings.ContainsAny(value, "\r\n") {
+			return ErrHeaderInjection
+		}
+	}
+	return nil
+}
+
+// unfold a header value, the CRLF of a folded line is removed and the whitespace after it is kept
+// checkControlChars guarantees that every CRLF left in the value is followed by a whitespace
+//
+// Returns the unfolded header value
+func unfoldHeader(str string) string {
+	return strings.ReplaceAll(str, "\r\n", "")
+}
+
+// CountAddresses counts how many addr-specs are in a "from:" string
+// Several addr-specs are not treated as an error
+//
+// Returns the number of emails in the pattern name@web.com with and without <>
+func CountAddresses(input string) int {
+	return countNoEmails(input)
+}
+
+// count how many email are in a "from:" string
+// "@" in quoted strings(display names) and in comments are not counted
+//
+// Return number of email in the pattern name@web.com with and without <>
+func countNoEmails(input string) int {
+	// a quoted local-part("john doe"@x.com) still counts, so the quoted strings are replaced and not removed
+	input = quotedStringRe.ReplaceAllString(input, "q")
+	input = removeNestedComments(input)
+
+	matches := addrCountRe.FindAllStringSubmatch(input, -1)
+
+	return len(matches)
+}
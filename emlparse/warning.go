@@ -0,0 +1,67 @@
+package emlparse
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// warnings of a valid address, listed in Address.Warnings
+const (
+	WarnObsoleteRoute  = "obsolete source route"
+	WarnComment        = "comment present"
+	WarnQuotedLocal    = "quoted local-part"
+	WarnNonASCIIDomain = "non-ASCII domain without IDN mode"
+	WarnNoDisplayName  = "missing display name"
+)
+
+// KnownWarnings lists every warning the parser can report,
+// a Parser with WarningsAsErrors rejects an address with any of them
+var KnownWarnings = []string{
+	WarnObsoleteRoute,
+	WarnComment,
+	WarnQuotedLocal,
+	WarnNonASCIIDomain,
+	WarnNoDisplayName,
+}
+
+// collect the non-fatal issues of a parsed address
+// The syntax is allowed by RFC 5322, but it is deprecated or can cause problems with other mail software
+// A comment after the address(jdoe@x.com (John Doe)) counts as display name
+//
+// Returns the list of warnings, empty if there are none
+func (p *Parser) collectWarnings(address Address, comment string, quotedLocal bool) []string {
+
+	warnings := []string{}
+
+	if address.ObsoleteRoute {
+		warnings = append(warnings, WarnObsoleteRoute)
+	}
+	if comment != "" {
+		warnings = append(warnings, WarnComment)
+	}
+	if quotedLocal {
+		warnings = append(warnings, WarnQuotedLocal)
+	}
+
+	domain := address.AddrSpec[strings.LastIndex(address.AddrSpec, "@")+1:]
+	if !p.IDN && !isDomainLiteral(address.AddrSpec) && strings.ContainsFunc(domain, func(r rune) bool { return r > unicode.MaxASCII }) {
+		warnings = append(warnings, WarnNonASCIIDomain)
+	}
+
+	if strings.TrimSpace(address.DisplayName) == "" && address.CommentName == "" {
+		warnings = append(warnings, WarnNoDisplayName)
+	}
+
+	return warnings
+}
+
+// promote the warnings of an address to an error, if the parser has WarningsAsErrors enabled
+//
+// Returns ErrWarning with the list of warnings or nil if there are none
+func (p *Parser) warningsError(address Address) error {
+	if !p.WarningsAsErrors || len(address.Warnings) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrWarning, strings.Join(address.Warnings, ", "))
+}
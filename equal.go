@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/linuxmk/eml-sender/emlparse"
+)
+
+// equalOutput structure contains the result of comparing two addresses with -equal
+type equalOutput struct {
+	Equal  bool   `json:"equal"`
+	Reason string `json:"reason"`
+}
+
+// compare two address strings, e.g. "Peter Pan" <peter@pan.com> and peter@PAN.com
+// They are equal when the addr-specs refer to the same mailbox after normalization, the display names are ignored
+// With ignoreDots the dots in the local-part are also ignored, like Gmail does(j.doe@gmail.com is jdoe@gmail.com)
+//
+// Returns the result of the comparison and an error if one of the addresses does not pass the validation
+func compareAddresses(parser *emlparse.Parser, first, second string, ignoreDots bool) (equalOutput, error) {
+
+	firstInfo, err := parser.ParseAddress(first)
+	if err != nil {
+		return equalOutput{Reason: fmt.Sprintf("first address: %v", err)}, err
+	}
+
+	secondInfo, err := parser.ParseAddress(second)
+	if err != nil {
+		return equalOutput{Reason: fmt.Sprintf("second address: %v", err)}, err
+	}
+
+	if firstInfo.AddrSpec == secondInfo.AddrSpec {
+		return equalOutput{Equal: true, Reason: "same addr-spec"}, nil
+	}
+
+	firstNorm := emlparse.NormalizeAddress(firstInfo.AddrSpec)
+	secondNorm := emlparse.NormalizeAddress(secondInfo.AddrSpec)
+	if firstNorm == secondNorm {
+		return equalOutput{Equal: true, Reason: "same addr-spec after lowercasing the domain"}, nil
+	}
+
+	if ignoreDots && stripLocalDots(firstNorm) == stripLocalDots(secondNorm) {
+		return equalOutput{Equal: true, Reason: "same addr-spec after removing the dots in the local-part"}, nil
+	}
+
+	return equalOutput{Equal: false, Reason: "different mailbox"}, nil
+}
+
+// remove the dots from the local-part of an addr-spec, the domain is not changed
+//
+// Returns the addr-spec without dots in the local-part
+func stripLocalDots(addrSpec string) string {
+	at := strings.LastIndex(addrSpec, "@")
+	if at < 0 {
+		return addrSpec
+	}
+	return strings.ReplaceAll(addrSpec[:at], ".", "") + addrSpec[at:]
+}
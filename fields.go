@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// parse the comma separated list of -fields and check every name against the json keys of the output
+// The key names of the -schema are converted back to the json keys, e.g. email to addr_spec
+//
+// Returns an error for an unknown field name or the list of field names, in the given order
+func parseFields(list string, schema map[string]string) ([]string, error) {
+
+	known := map[string]bool{}
+	for _, name := range jsonFieldNames(reflect.TypeOf(jsonOutput{})) {
+		known[name] = true
+	}
+
+	fields := []string{}
+	for _, name := range strings.Split(list, ",") {
+		name = schemaSource(schema, strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !known[name] {
+			return nil, fmt.Errorf("unknown field %q in -fields", name)
+		}
+		fields = append(fields, name)
+	}
+
+	return fields, nil
+}
+
+// collect the json keys of a structure, the fields of embedded structures are included
+//
+// Returns the list of json keys in the order of the structure fields
+func jsonFieldNames(t reflect.Type) []string {
+
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	names := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous {
+			names = append(names, jsonFieldNames(field.Type)...)
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+// build a json object with only the selected fields of a record, in the order of the fields
+// A field that is not set in the record(e.g. omitted because it is empty) is written as null
+//
+// Returns the json object
+func selectFields(record jsonOutput, fields []string) json.RawMessage {
+
+	values := map[string]json.RawMessage{}
+	data, _ := json.Marshal(record)
+	json.Unmarshal(data, &values)
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, name := range fields {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(name)
+		buf.Write(key)
+		buf.WriteByte(':')
+		if value, ok := values[name]; ok {
+			buf.Write(value)
+		} else {
+			buf.WriteString("null")
+		}
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes()
+}
+
+// convert a record to the value that is written to the output
+//
+// Returns the record or, with -fields, a json object with only the selected fields,
+// the keys are renamed with -schema
+func outputRecord(record jsonOutput, fields []string, schema map[string]string) any {
+
+	if len(fields) == 0 && schema == nil {
+		return record
+	}
+
+	var object []byte
+	if len(fields) == 0 {
+		object, _ = json.Marshal(record)
+	} else {
+		object = selectFields(record, fields)
+	}
+	if schema == nil {
+		return json.RawMessage(object)
+	}
+
+	return renameKeys(object, schema)
+}
@@ -0,0 +1,8 @@
+module github.com/linuxmk/eml-sender
+
+go 1.23.0
+
+require (
+	golang.org/x/net v0.40.0
+	golang.org/x/text v0.25.0
+)
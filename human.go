@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/linuxmk/eml-sender/emlparse"
+)
+
+// ANSI escape sequences of the -human colors
+const (
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorRed    = "\x1b[31m"
+	colorReset  = "\x1b[0m"
+)
+
+// humanOutput structure writes the extracted data in a readable form for -human, one line for every address
+// The lines are colored when the output is a terminal: green for a valid address,
+// yellow for an address with warnings and red for an error
+type humanOutput struct {
+	w     io.Writer
+	color bool
+}
+
+// create a human readable output that writes to w
+// The colors are disabled when w is not a terminal(e.g. piped or -out) or NO_COLOR is set
+//
+// Returns the human readable output
+func newHumanOutput(w io.Writer) *humanOutput {
+	return &humanOutput{w: w, color: isTerminal(w) && os.Getenv("NO_COLOR") == ""}
+}
+
+// write one line for every json structure, e.g. mails/a.eml: Peter Pan <peter@pan.com>
+//
+// Returns an error if the lines can not be written
+func (h *humanOutput) write(jsonOut []jsonOutput) error {
+
+	for _, record := range jsonOut {
+		var sb strings.Builder
+		if record.Filename != "" {
+			sb.WriteString(record.Filename + ": ")
+		}
+		if record.Message > 0 {
+			fmt.Fprintf(&sb, "message %d: ", record.Message)
+		}
+
+		color := colorGreen
+		switch {
+		case record.Error != nil:
+			color = colorRed
+			sb.WriteString("error: " + *record.Error)
+		case record.Empty:
+			sb.WriteString("(empty header)")
+		case record.Email == "" && record.Group != "":
+			sb.WriteString(record.Group + ": (no members)")
+		case record.NullPath:
+			sb.WriteString("<>")
+		default:
+			sb.WriteString(emlparse.FormatAddress(record.Name, stripBrackets(record.Email)))
+		}
+
+		if record.validityOutput != nil && len(record.Warnings) > 0 {
+			color = colorYellow
+			sb.WriteString(" (" + strings.Join(record.Warnings, ", ") + ")")
+		}
+		if record.Count > 0 {
+			fmt.Fprintf(&sb, " x%d", record.Count)
+		}
+
+		line := sb.String()
+		if h.color {
+			line = color + line + colorReset
+		}
+		if _, err := fmt.Fprintln(h.w, line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// check if the output is written to a terminal(a character device)
+//
+// Returns false for files, pipes and other writers
+func isTerminal(w io.Writer) bool {
+
+	fd, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := fd.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/linuxmk/eml-sender/emlparse"
+)
+
+// errNoFromField is returned for a json line without the "from" string
+var errNoFromField = errors.New("\"from\" field missing")
+
+// jsonLine structure contains a line of the -input=jsonl input, e.g. {"from": "Alice <a@x.com>"}
+type jsonLine struct {
+	From *string `json:"from"`
+}
+
+// parse the "from" field of every line of a json lines file(or stdin with "-")
+// and output the records like for a test file
+// A line that is not valid json does not stop the run, it is output as a record with the error
+// Blank lines are skipped, in -check mode only the errors are printed to stderr
+//
+// Returns an error if the file can not be read
+// or errCheckFailed if at least one line fails in -check mode
+func doJSONLines(parser *emlparse.Parser, filename string, opts outputOptions) error {
+
+	r, closer, err := openInput(filename)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	failed := false
+	number := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	for scanner.Scan() {
+		number++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		fromStr, err := decodeJSONLine(line)
+		var infos []emlparse.Address
+		if err != nil {
+			err = fmt.Errorf("line %d: %w", number, err)
+		} else {
+			infos, err = parser.ParseAddressList(fromStr)
+		}
+
+		opts.stats.add(err)
+		if opts.check {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", line, err)
+				failed = true
+			}
+			continue
+		}
+
+		jsonOut := buildJSONOutput(infos, err, opts)
+		annotateHeader(jsonOut, nil, headerData{value: fromStr}, opts)
+		printOutput(jsonOut, opts)
+	}
+
+	if err := scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return errLineTooLong
+		}
+		return err
+	}
+
+	if failed {
+		return errCheckFailed
+	}
+
+	return nil
+}
+
+// decode a single line of the -input=jsonl input
+//
+// Returns an error if the line is not a json object with a "from" string or the value of "from"
+func decodeJSONLine(line string) (string, error) {
+
+	var value jsonLine
+	if err := json.Unmarshal([]byte(line), &value); err != nil {
+		return "", fmt.Errorf("invalid json: %w", err)
+	}
+	if value.From == nil {
+		return "", errNoFromField
+	}
+
+	return *value.From, nil
+}
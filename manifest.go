@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"strings"
+
+	"github.com/linuxmk/eml-sender/emlparse"
+)
+
+// parse the header of every file listed in a manifest file
+// and output one record with the filename for every file, in the order of the manifest
+// A file that can not be read is reported in the record of the file, or skipped or the run aborted with -on-error
+// With -dry-run only the paths of the files are printed, one per line, and nothing is parsed
+//
+// Returns an error if the manifest can not be read or -on-error=abort stopped the run,
+// or errCheckFailed if at least one file fails in -check mode
+func doManifest(parser *emlparse.Parser, manifest string, headers []string, workers int, opts outputOptions) error {
+
+	files, err := readManifest(manifest)
+	if err != nil {
+		return err
+	}
+
+	if opts.dryRun {
+		printPaths(files, opts)
+		return nil
+	}
+
+	return parseFiles(parser, files, headers, workers, opts)
+}
+
+// read the paths of the files from a manifest file, one path per line
+// Blank lines and comment lines starting with "#" are skipped
+//
+// Returns an error if the manifest can not be read or the list of paths
+func readManifest(filename string) ([]string, error) {
+
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	files := []string{}
+
+	scanner := bufio.NewScanner(fd)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineLength)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		files = append(files, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/linuxmk/eml-sender/emlparse"
+)
+
+// parse the header of every message of an mbox file(or stdin with "-")
+// and output the records of every message with the number of the message, starting at 1
+// The messages are read one by one, so the file is never loaded into memory as a whole
+// An error in a single message does not stop the run, it is reported in the record of the message
+//
+// Returns an error if the file can not be read
+// or errCheckFailed if at least one message fails in -check mode
+func doMbox(parser *emlparse.Parser, filename string, headers []string, opts outputOptions) error {
+
+	r, closer, err := openInput(filename)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	index := 0
+	failed := false
+
+	err = emlparse.SplitMessages(emlparse.DecodeInput(r), func(header string) {
+		index++
+
+		data, err := locateHeader(strings.NewReader(header), headers)
+		//only the header section is passed, so a message is never empty, its header section is
+		if errors.Is(err, emlparse.ErrEmptyInput) {
+			err = &parseError{exitCode: exitHeaderMissing, err: emlparse.ErrEmptyHeaders}
+		}
+		var senderInfo []emlparse.Address
+		if err == nil {
+			senderInfo, err = parseHeaderValue(parser, data)
+		}
+		opts.stats.add(err)
+
+		if opts.check {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "message %d: %v\n", index, err)
+				failed = true
+			}
+			return
+		}
+
+		jsonOut := buildJSONOutput(senderInfo, err, opts)
+		annotateHeader(jsonOut, headers, data, opts)
+		for i := range jsonOut {
+			jsonOut[i].Message = index
+		}
+		printOutput(jsonOut, opts)
+	})
+	if err != nil {
+		return err
+	}
+
+	if failed {
+		return errCheckFailed
+	}
+
+	return nil
+}
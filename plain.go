@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// plainOutput structure writes only the addr_spec of every address for -format=plain, one per line,
+// a flat address list for grep or sort -u
+// The records with an error are written to the error writer(stderr), so they do not mix with the addresses
+type plainOutput struct {
+	w      io.Writer
+	errOut io.Writer
+}
+
+// write the addr_spec of every json structure, an empty header and the null return path <> have no address
+// An error is written as the filename or message number and the error message, e.g. mails/a.eml: no addr-spec found
+//
+// Returns an error if the lines can not be written
+func (p *plainOutput) write(jsonOut []jsonOutput) error {
+
+	for _, record := range jsonOut {
+		if record.Error != nil {
+			prefix := ""
+			if record.Filename != "" {
+				prefix = record.Filename + ": "
+			}
+			if record.Message > 0 {
+				prefix += fmt.Sprintf("message %d: ", record.Message)
+			}
+			fmt.Fprintln(p.errOut, prefix+*record.Error)
+			continue
+		}
+
+		if record.Email == "" {
+			continue
+		}
+		if _, err := fmt.Fprintln(p.w, record.Email); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
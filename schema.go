@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// shortSchema maps the json keys to the short names of -schema=short,
+// the other keys(e.g. error) are the same in every schema
+var shortSchema = map[string]string{
+	"display_name": "name",
+	"addr_spec":    "email",
+}
+
+// convert the value of -schema to the key names of the output
+//
+// Returns an error for an unknown schema or the key mapping, nil for the default schema
+func parseSchema(name string) (map[string]string, error) {
+	switch name {
+	case "", "default":
+		return nil, nil
+	case "short":
+		return shortSchema, nil
+	}
+	return nil, fmt.Errorf("unknown -schema %q, use default or short", name)
+}
+
+// convert a key name of the schema back to the json key of the output structure
+//
+// Returns the json key, the name itself if the schema does not rename it
+func schemaSource(schema map[string]string, name string) string {
+	for key, renamed := range schema {
+		if renamed == name {
+			return key
+		}
+	}
+	return name
+}
+
+// convert a json key of the output structure to the key name of the schema
+//
+// Returns the key name, the key itself if the schema does not rename it
+func schemaKey(schema map[string]string, key string) string {
+	if renamed, ok := schema[key]; ok {
+		return renamed
+	}
+	return key
+}
+
+// rename the keys of a json object to the key names of the schema, the order of the keys is kept
+//
+// Returns the json object with the renamed keys
+func renameKeys(object []byte, schema map[string]string) json.RawMessage {
+
+	values := []json.RawMessage{}
+	keys := []string{}
+
+	dec := json.NewDecoder(bytes.NewReader(object))
+	if _, err := dec.Token(); err != nil {
+		return object
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return object
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return object
+		}
+		keys = append(keys, schemaKey(schema, token.(string)))
+		values = append(values, value)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(key)
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(values[i])
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/linuxmk/eml-sender/emlparse"
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// recordSorter structure sorts the records of a batch run for -sort
+// The strings are compared with the collation of the -locale, case-insensitive,
+// so that "Ä" sorts near "A" and not after "Z" like in byte order
+type recordSorter struct {
+	by       string //name or addr
+	collator *collate.Collator
+}
+
+// create the sorter for the values of -sort and -locale
+//
+// Returns an error for an unknown sort key or a malformed locale
+func newRecordSorter(by string, locale string) (*recordSorter, error) {
+
+	if by != "name" && by != "addr" {
+		return nil, fmt.Errorf("unknown -sort %q, use name or addr", by)
+	}
+
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -locale %q: %w", locale, err)
+	}
+
+	return &recordSorter{by: by, collator: collate.New(tag, collate.IgnoreCase)}, nil
+}
+
+// sort the records by the display name or the address, the other value breaks a tie
+// The records with an error are moved to the end, in the order they occurred
+//
+// Return void
+func (s *recordSorter) sort(records []jsonOutput) {
+
+	slices.SortStableFunc(records, func(a, b jsonOutput) int {
+		switch {
+		case a.Error != nil || b.Error != nil:
+			return boolOrder(a.Error != nil) - boolOrder(b.Error != nil)
+		case s.by == "name":
+			if c := s.collator.CompareString(a.Name, b.Name); c != 0 {
+				return c
+			}
+			return s.compareAddr(a, b)
+		}
+		if c := s.compareAddr(a, b); c != 0 {
+			return c
+		}
+		return s.collator.CompareString(a.Name, b.Name)
+	})
+}
+
+// compare the addresses of two records in the -normalize form
+//
+// Returns -1, 0 or 1 like strings.Compare
+func (s *recordSorter) compareAddr(a, b jsonOutput) int {
+	return s.collator.CompareString(emlparse.NormalizeAddress(stripBrackets(a.Email)), emlparse.NormalizeAddress(stripBrackets(b.Email)))
+}
+
+// convert a bool to a number for comparing
+//
+// Returns 1 for true and 0 for false
+func boolOrder(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
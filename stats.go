@@ -0,0 +1,52 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/linuxmk/eml-sender/emlparse"
+)
+
+// batchStats structure contains the counts of a batch run(directory, mbox file or test file) for -stats
+type batchStats struct {
+	Total         int `json:"total"`
+	Parsed        int `json:"parsed"`
+	Invalid       int `json:"invalid"`
+	HeaderMissing int `json:"header_missing"`
+	IOErrors      int `json:"io_errors"`
+}
+
+// count the result of a single file, message or test string
+//
+// Return void
+func (s *batchStats) add(err error) {
+	if s == nil {
+		return
+	}
+
+	s.Total++
+
+	switch {
+	case err == nil:
+		s.Parsed++
+	case errors.Is(err, emlparse.ErrHeaderMissing) || errors.Is(err, emlparse.ErrEmptyHeaders) || errors.Is(err, emlparse.ErrEmptyInput):
+		s.HeaderMissing++
+	case isIOError(err):
+		s.IOErrors++
+	default:
+		s.Invalid++
+	}
+}
+
+// write the summary of the batch run as a single compact json line, nothing without -stats
+//
+// Return void
+func (s *batchStats) print(w io.Writer) {
+	if s == nil {
+		return
+	}
+	fmt.Fprintf(w, "%s\n", createJSONOutput(struct {
+		Stats *batchStats `json:"stats"`
+	}{s}, ""))
+}
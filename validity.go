@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/linuxmk/eml-sender/emlparse"
+)
+
+// validityOutput structure contains the classification of an address for -validity
+// It is embedded in jsonOutput, so its fields are written inline
+type validityOutput struct {
+	Validity string   `json:"validity"`
+	Warnings []string `json:"warnings"`
+}
+
+// classify a parsed address as valid, valid-with-warnings or invalid
+// The warnings are the non-fatal issues found by the parser, e.g. a comment or an obsolete source route
+//
+// Returns the classification, invalid with no warnings if the address does not pass the validation
+func classify(info emlparse.Address, err error) *validityOutput {
+
+	if err != nil {
+		return &validityOutput{Validity: "invalid", Warnings: []string{}}
+	}
+
+	warnings := info.Warnings
+	if warnings == nil {
+		warnings = []string{}
+	}
+
+	if len(warnings) > 0 {
+		return &validityOutput{Validity: "valid-with-warnings", Warnings: warnings}
+	}
+	return &validityOutput{Validity: "valid", Warnings: warnings}
+}